@@ -30,7 +30,7 @@ echo Building for %1-%2
 cd src
 set GOOS=%1
 set GOARCH=%2
-go build -o "%output%" main.go
+go build -o "%output%" .
 echo Built "%output%"
 cd ..
 goto end
@@ -11,7 +11,7 @@ build() {
     mkdir -p $(dirname $output)
     echo "Building for $os-$arch"
     cd src
-    GOOS=$os GOARCH=$arch go build -o $output main.go
+    GOOS=$os GOARCH=$arch go build -o $output .
     echo "Built $output"
     cd ..
 }
@@ -1,18 +1,265 @@
 package config
 
-// Config holds all configuration settings for the scraper
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config holds all configuration settings for the scraper. Run reports save it as JSON,
+// with the API key redacted, see Redacted.
 type Config struct {
 	// Output configuration
-	OutputFormat  string // json, jsonl, or markdown
-	OutputDir     string // Where to store the data
+	OutputFormat string `json:"output_format"` // json, jsonl, markdown, or a comma-separated combination
+	OutputDir    string `json:"output_dir"`    // Where to store the data
+	Backend      string `json:"backend"`       // Where jam data and submissions are stored: files or sqlite
+
+	// Pipeline configuration. Each stage of the game pipeline has its own worker pool,
+	// so HTML scraping and CDN downloads can be tuned separately.
+	Workers      int `json:"workers"`       // Workers scraping rate pages and game pages
+	WriteWorkers int `json:"write_workers"` // Workers saving scraped details to disk
+	MediaWorkers int `json:"media_workers"` // Workers downloading media and game files
+	QueueSize    int `json:"queue_size"`    // Capacity of the channels between stages
 
 	// Network configuration
-	Workers       int    // Number of concurrent workers
-	UserAgent     string // User agent string for HTTP requests
-	RequestDelay  int    // Delay between requests in milliseconds (default: 1500)
+	UserAgent            string  `json:"user_agent"`              // User agent string for HTTP requests
+	RequestDelay         int     `json:"request_delay"`           // Delay between requests in milliseconds (default: 1500)
+	RequestsPerSecond    float64 `json:"requests_per_second"`     // Shared request rate for itch.io pages; derived from RequestDelay when zero
+	Burst                int     `json:"burst"`                   // Number of itch.io requests allowed back to back
+	CDNRequestsPerSecond float64 `json:"cdn_requests_per_second"` // Shared request rate for media downloads from CDN hosts
+	CDNBurst             int     `json:"cdn_burst"`               // Number of CDN downloads allowed back to back
+	BaseURL              string  `json:"base_url"`                // Base URL of itch.io, overridable to point at a mock server
+
+	// Authentication. Credentials are never written to logs or output.
+	APIKey     string `json:"api_key"`     // itch.io API key, sent only to APIURL
+	APIURL     string `json:"api_url"`     // Base URL of the itch.io API
+	CookieFile string `json:"cookie_file"` // Netscape cookies.txt exported from a logged-in browser session
+
+	// Retry configuration
+	MaxAttempts    int `json:"max_attempts"`     // Maximum attempts per request, including the first one
+	RetryBaseDelay int `json:"retry_base_delay"` // Delay before the first retry in milliseconds, doubled on each retry
+	RetryMaxDelay  int `json:"retry_max_delay"`  // Upper bound for the delay between retries in milliseconds
+
+	// Entry selection, applied to each jam's entry list before anything is scraped
+	Platforms     []string `json:"platforms"`      // Only entries available on one of these platforms, such as web or windows
+	MinRatings    int      `json:"min_ratings"`    // Only entries with at least this many ratings
+	MinCoolness   int      `json:"min_coolness"`   // Only entries with at least this much coolness
+	CreatedAfter  string   `json:"created_after"`  // Only entries submitted at or after this date or time
+	CreatedBefore string   `json:"created_before"` // Only entries submitted before this date or time
+	TitlePattern  string   `json:"title_pattern"`  // Only entries whose title matches this regular expression
+	AuthorPattern string   `json:"author_pattern"` // Only entries with an author or contributor matching this regular expression
+	SortBy        string   `json:"sort_by"`        // Sort key: coolness, ratings, created or title, optionally suffixed with :asc or :desc
+	Limit         int      `json:"limit"`          // Maximum number of entries to scrape after sorting; zero means no limit
+	SampleSize    int      `json:"sample_size"`    // Random sample of this many entries, taken after filtering; zero disables sampling
+	SampleSeed    int64    `json:"sample_seed"`    // Seed for the random sample, so a sample can be reproduced
+
+	// Run budgets; zero disables a budget. Running out of requests, total bytes or disk
+	// space stops the run; files over MaxFileBytes are skipped.
+	MaxRequests   int64 `json:"max_requests"`    // Maximum HTTP requests in the run, retries included
+	MaxFileBytes  int64 `json:"max_file_bytes"`  // Maximum size of a single downloaded file
+	MaxTotalBytes int64 `json:"max_total_bytes"` // Maximum bytes downloaded in the run
+	MinFreeDisk   int64 `json:"min_free_disk"`   // Free space to keep on the disk holding OutputDir
 
 	// Feature flags
-	DownloadMedia bool // Whether to download media files
-	DownloadGames bool // Whether to download game files
+	DownloadMedia bool `json:"download_media"`  // Whether to download media files
+	DownloadGames bool `json:"download_games"`  // Whether to download game files
+	FetchResults  bool `json:"fetch_results"`   // Whether to fetch the results and rankings of finished jams
+	FetchGamePage bool `json:"fetch_game_page"` // Whether to scrape each game's own page for tags, genre, engine and other details
+	Resume        bool `json:"resume"`          // Whether to skip games finished by an earlier run and retry failed ones
+	Incremental   bool `json:"incremental"`     // Whether to refetch details only for entries that changed since the stored game.json
+}
+
+// DefaultBaseURL is the base URL used for all itch.io requests
+const DefaultBaseURL = "https://itch.io"
+
+// DefaultAPIURL is the base URL of the itch.io API used with an API key
+const DefaultAPIURL = "https://api.itch.io"
+
+// Supported output formats. Submissions always go to the storage backend, so json
+// adds nothing to it; jsonl and markdown are written in addition.
+const (
+	FormatJSON     = "json"
+	FormatJSONL    = "jsonl"
+	FormatMarkdown = "markdown"
+)
+
+// Formats returns the list of output formats requested in OutputFormat
+func (c Config) Formats() []string {
+	var formats []string
+	for _, format := range strings.Split(c.OutputFormat, ",") {
+		format = strings.ToLower(strings.TrimSpace(format))
+		if format != "" {
+			formats = append(formats, format)
+		}
+	}
+	return formats
+}
+
+// HasFormat reports whether the given output format was requested
+func (c Config) HasFormat(format string) bool {
+	for _, f := range c.Formats() {
+		if f == format {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateFormats checks that every requested output format is supported
+func (c Config) ValidateFormats() error {
+	formats := c.Formats()
+	if len(formats) == 0 {
+		return fmt.Errorf("no output format given")
+	}
+	for _, format := range formats {
+		switch format {
+		case FormatJSON, FormatJSONL, FormatMarkdown:
+		default:
+			return fmt.Errorf("unsupported output format: %s", format)
+		}
+	}
+	return nil
+}
+
+// Storage backends
+const (
+	BackendFiles  = "files"  // JSON files in the output directory
+	BackendSQLite = "sqlite" // An SQLite database in the output directory
+)
+
+// Backends lists the supported storage backends
+var Backends = []string{BackendFiles, BackendSQLite}
+
+// ValidateBackend checks that the storage backend is supported
+func (c Config) ValidateBackend() error {
+	for _, backend := range Backends {
+		if c.Backend == backend {
+			return nil
+		}
+	}
+	return fmt.Errorf("unsupported storage backend: %s (supported: %s)", c.Backend, strings.Join(Backends, ", "))
+}
+
+// Sort keys for entry selection
+const (
+	SortCoolness = "coolness"
+	SortRatings  = "ratings"
+	SortCreated  = "created"
+	SortTitle    = "title"
+)
+
+// EntryTimeLayout is the layout of the created_at field of jam entries
+const EntryTimeLayout = "2006-01-02 15:04:05"
+
+// SortOrder splits SortBy into its key and direction. Without a direction, numbers
+// and dates sort highest and newest first and titles sort alphabetically.
+func (c Config) SortOrder() (key string, descending bool, err error) {
+	key, direction, _ := strings.Cut(strings.ToLower(strings.TrimSpace(c.SortBy)), ":")
+	switch key {
+	case "":
+		return "", false, nil
+	case SortCoolness, SortRatings, SortCreated:
+		descending = true
+	case SortTitle:
+	default:
+		return "", false, fmt.Errorf("unsupported sort key: %s", key)
+	}
+
+	switch direction {
+	case "":
+	case "asc":
+		descending = false
+	case "desc":
+		descending = true
+	default:
+		return "", false, fmt.Errorf("unsupported sort direction: %s", direction)
+	}
+	return key, descending, nil
+}
+
+// CreatedWindow parses CreatedAfter and CreatedBefore, returning zero times for bounds that are not set
+func (c Config) CreatedWindow() (after, before time.Time, err error) {
+	if after, err = parseSelectionTime(c.CreatedAfter); err != nil {
+		return after, before, fmt.Errorf("invalid created-after time: %w", err)
+	}
+	if before, err = parseSelectionTime(c.CreatedBefore); err != nil {
+		return after, before, fmt.Errorf("invalid created-before time: %w", err)
+	}
+	return after, before, nil
+}
+
+// parseSelectionTime parses a date, a date and time as used by jam entries, or an RFC 3339 timestamp
+func parseSelectionTime(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}, nil
+	}
+	for _, layout := range []string{time.RFC3339, EntryTimeLayout, "2006-01-02"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("%q is not a date (2006-01-02) or time (2006-01-02 15:04:05)", value)
+}
+
+// ValidateSelection checks the entry selection options
+func (c Config) ValidateSelection() error {
+	if _, _, err := c.SortOrder(); err != nil {
+		return err
+	}
+	if _, _, err := c.CreatedWindow(); err != nil {
+		return err
+	}
+	for name, pattern := range map[string]string{"title": c.TitlePattern, "author": c.AuthorPattern} {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return fmt.Errorf("invalid %s pattern: %w", name, err)
+		}
+	}
+	if c.Limit < 0 || c.SampleSize < 0 {
+		return fmt.Errorf("limit and sample size must not be negative")
+	}
+	return nil
 }
 
+// ParseByteSize parses a size such as "1024", "300 kB", "500MB" or "2 GiB". Units are
+// powers of 1024, with or without the "i", as itch.io shows them.
+func ParseByteSize(size string) (int64, error) {
+	size = strings.TrimSpace(size)
+	number := strings.TrimRightFunc(size, func(r rune) bool {
+		return r < '0' || r > '9'
+	})
+	unit := strings.ToLower(strings.TrimSpace(size[len(number):]))
+
+	value, err := strconv.ParseFloat(strings.TrimSpace(number), 64)
+	if err != nil || value < 0 {
+		return 0, fmt.Errorf("invalid size: %q", size)
+	}
+
+	var multiplier float64
+	switch unit {
+	case "", "b", "bytes":
+		multiplier = 1
+	case "k", "kb", "kib":
+		multiplier = 1 << 10
+	case "m", "mb", "mib":
+		multiplier = 1 << 20
+	case "g", "gb", "gib":
+		multiplier = 1 << 30
+	case "t", "tb", "tib":
+		multiplier = 1 << 40
+	default:
+		return 0, fmt.Errorf("invalid size unit in %q", size)
+	}
+
+	return int64(value * multiplier), nil
+}
+
+// Redacted returns a copy of the configuration that is safe to log or save
+func (c Config) Redacted() Config {
+	if c.APIKey != "" {
+		c.APIKey = "REDACTED"
+	}
+	return c
+}
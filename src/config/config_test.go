@@ -0,0 +1,73 @@
+package config
+
+import "testing"
+
+func TestParseByteSize(t *testing.T) {
+	tests := []struct {
+		size    string
+		want    int64
+		wantErr bool
+	}{
+		{"0", 0, false},
+		{"1024", 1024, false},
+		{"12 B", 12, false},
+		{"300 bytes", 300, false},
+		{"300 kB", 300 << 10, false},
+		{"1.5 KiB", 1536, false},
+		{"500MB", 500 << 20, false},
+		{"2 GiB", 2 << 30, false},
+		{"5gb", 5 << 30, false},
+		{"1 TB", 1 << 40, false},
+		{" 42 M ", 42 << 20, false},
+		{"", 0, true},
+		{"MB", 0, true},
+		{"-5 MB", 0, true},
+		{"12 parsecs", 0, true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseByteSize(tt.size)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ParseByteSize(%q) = %d, want an error", tt.size, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseByteSize(%q): %v", tt.size, err)
+		} else if got != tt.want {
+			t.Errorf("ParseByteSize(%q) = %d, want %d", tt.size, got, tt.want)
+		}
+	}
+}
+
+func TestFormats(t *testing.T) {
+	tests := []struct {
+		output  string
+		want    []string
+		wantErr bool
+	}{
+		{"json", []string{"json"}, false},
+		{"JSON, jsonl,markdown", []string{"json", "jsonl", "markdown"}, false},
+		{" , ", nil, true},
+		{"json,xml", []string{"json", "xml"}, true},
+	}
+
+	for _, tt := range tests {
+		cfg := Config{OutputFormat: tt.output}
+		got := cfg.Formats()
+		if len(got) != len(tt.want) {
+			t.Errorf("Formats() of %q = %v, want %v", tt.output, got, tt.want)
+		} else {
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("Formats() of %q = %v, want %v", tt.output, got, tt.want)
+					break
+				}
+			}
+		}
+		if err := cfg.ValidateFormats(); (err != nil) != tt.wantErr {
+			t.Errorf("ValidateFormats() of %q = %v, want error %v", tt.output, err, tt.wantErr)
+		}
+	}
+}
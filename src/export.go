@@ -0,0 +1,84 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"Itchalyser/fsutil"
+	"Itchalyser/storage"
+)
+
+// runExport is the export command. It writes the submissions of scraped jams to a
+// single file, one row or line per submission, without touching the network.
+func runExport(args []string) int {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	archive := addArchiveFlags(fs)
+	format := fs.String("format", storage.ExportCSV, "Export format: "+strings.Join(storage.ExportFormats, ", "))
+	output := fs.String("o", "", "File to write, or - for standard output (default: exports/<jam>.<format> in the output directory)")
+	fs.Parse(args)
+
+	if !slices.Contains(storage.ExportFormats, *format) {
+		log.Fatalf("Unknown export format: %s (supported: %s)", *format, strings.Join(storage.ExportFormats, ", "))
+	}
+
+	_, backend, jams := archive.open()
+	defer backend.Close()
+
+	write := func(w io.Writer) error {
+		switch *format {
+		case storage.ExportJSONL:
+			return storage.WriteJSONL(w, jams)
+		case storage.ExportJSON:
+			return storage.WriteJSON(w, jams)
+		}
+		return storage.WriteCSV(w, jams)
+	}
+
+	if *output == "-" {
+		if err := write(os.Stdout); err != nil {
+			log.Printf("Error writing export: %v", err)
+			return exitFailed
+		}
+		return exitOK
+	}
+
+	path := *output
+	if path == "" {
+		name := "jams"
+		if len(jams) == 1 {
+			name = jams[0].ID
+		}
+		path = filepath.Join(*archive.dir, "exports", name+"."+*format)
+	}
+
+	if err := exportToFile(path, write); err != nil {
+		log.Printf("Error writing export: %v", err)
+		return exitFailed
+	}
+
+	fmt.Printf("Exported the submissions of %d jams to %s\n", len(jams), path)
+	return exitOK
+}
+
+// exportToFile writes an export to a temporary file and renames it into place
+func exportToFile(path string, write func(io.Writer) error) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	file, err := fsutil.CreateTemp(path)
+	if err != nil {
+		return err
+	}
+	if err := write(file); err != nil {
+		fsutil.Discard(file)
+		return err
+	}
+	return fsutil.Commit(file, path)
+}
@@ -0,0 +1,85 @@
+package fetcher
+
+import (
+	"bufio"
+	"fmt"
+	"net/http"
+	neturl "net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LoadCookieFile loads cookies exported from a logged-in browser session in the
+// Netscape cookies.txt format into the session shared by all requests. Cookie
+// values are never logged.
+func (f *JamFetcher) LoadCookieFile(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	loaded := 0
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+
+		// Browsers mark HttpOnly cookies with a prefix on an otherwise commented line
+		httpOnly := false
+		if strings.HasPrefix(text, "#HttpOnly_") {
+			text = strings.TrimPrefix(text, "#HttpOnly_")
+			httpOnly = true
+		}
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		fields := strings.Split(text, "\t")
+		if len(fields) != 7 {
+			return fmt.Errorf("%s:%d: expected 7 tab-separated fields, got %d", path, line, len(fields))
+		}
+
+		domain := strings.TrimPrefix(fields[0], ".")
+		cookie := &http.Cookie{
+			Name:     fields[5],
+			Value:    fields[6],
+			Path:     fields[2],
+			Domain:   fields[0],
+			Secure:   strings.EqualFold(fields[3], "TRUE"),
+			HttpOnly: httpOnly,
+		}
+		if expiry, err := strconv.ParseInt(fields[4], 10, 64); err == nil && expiry > 0 {
+			cookie.Expires = time.Unix(expiry, 0)
+		}
+
+		scheme := "http"
+		if cookie.Secure {
+			scheme = "https"
+		}
+		f.client.Jar.SetCookies(&neturl.URL{Scheme: scheme, Host: domain, Path: "/"}, []*http.Cookie{cookie})
+		loaded++
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	if loaded == 0 {
+		return fmt.Errorf("no cookies found in %s", path)
+	}
+
+	return nil
+}
+
+// authorize adds the API key to requests for the itch.io API. The key is only ever
+// sent to the API host; Go's client drops it again on redirects to other hosts.
+func (f *JamFetcher) authorize(req *http.Request) {
+	if f.apiKey != "" && req.URL.Host == f.apiHost {
+		req.Header.Set("Authorization", "Bearer "+f.apiKey)
+	}
+}
+
+// HasAPIKey reports whether requests to the itch.io API are authenticated
+func (f *JamFetcher) HasAPIKey() bool {
+	return f.apiKey != ""
+}
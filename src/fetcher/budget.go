@@ -0,0 +1,191 @@
+package fetcher
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"path/filepath"
+	"sync"
+
+	"Itchalyser/config"
+	"Itchalyser/fsutil"
+)
+
+// ErrBudgetExhausted is wrapped by the errors of requests and downloads refused because a
+// run budget is used up. Once returned, every later request fails the same way.
+var ErrBudgetExhausted = errors.New("run budget exhausted")
+
+// ErrFileTooLarge is wrapped by the errors of downloads over the per-file size budget.
+// Only that file is skipped; the run goes on.
+var ErrFileTooLarge = errors.New("file exceeds the maximum file size")
+
+// BudgetError describes which budget refused a request or download
+type BudgetError struct {
+	Budget string // requests, file size, total bytes or free disk
+	Limit  int64
+	err    error
+}
+
+func (e *BudgetError) Error() string {
+	switch e.Budget {
+	case "requests":
+		return fmt.Sprintf("request budget of %d requests exhausted", e.Limit)
+	case "file size":
+		return fmt.Sprintf("file is larger than the maximum file size of %d bytes", e.Limit)
+	case "total bytes":
+		return fmt.Sprintf("download budget of %d bytes exhausted", e.Limit)
+	case "free disk":
+		return fmt.Sprintf("less than %d bytes of free disk space left", e.Limit)
+	}
+	return e.err.Error()
+}
+
+func (e *BudgetError) Unwrap() error {
+	return e.err
+}
+
+// budget tracks a run's usage against the budgets of the configuration. It is shared by
+// every goroutine using the fetcher.
+type budget struct {
+	mu            sync.Mutex
+	maxRequests   int64
+	maxFileBytes  int64
+	maxTotalBytes int64
+	minFreeDisk   int64
+	requests      int64
+	totalBytes    int64
+	exhausted     *BudgetError // First run budget that ran out
+	diskUnknown   bool         // Free disk space could not be read, which was logged once
+}
+
+// newBudget creates a budget from the configuration
+func newBudget(cfg config.Config) *budget {
+	return &budget{
+		maxRequests:   cfg.MaxRequests,
+		maxFileBytes:  cfg.MaxFileBytes,
+		maxTotalBytes: cfg.MaxTotalBytes,
+		minFreeDisk:   cfg.MinFreeDisk,
+	}
+}
+
+// exhaust records that a run budget ran out and returns its error. Must be called with mu held.
+func (b *budget) exhaust(name string, limit int64) error {
+	if b.exhausted == nil {
+		b.exhausted = &BudgetError{Budget: name, Limit: limit, err: ErrBudgetExhausted}
+	}
+	return b.exhausted
+}
+
+// takeRequest counts a request, failing once the request budget is used up
+func (b *budget) takeRequest() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.exhausted != nil {
+		return b.exhausted
+	}
+	if b.maxRequests > 0 && b.requests >= b.maxRequests {
+		return b.exhaust("requests", b.maxRequests)
+	}
+	b.requests++
+	return nil
+}
+
+// checkDownload checks a download of the given size, or -1 if unknown, into destPath
+// before it starts
+func (b *budget) checkDownload(size int64, destPath string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.exhausted != nil {
+		return b.exhausted
+	}
+	if b.maxFileBytes > 0 && size > b.maxFileBytes {
+		return &BudgetError{Budget: "file size", Limit: b.maxFileBytes, err: ErrFileTooLarge}
+	}
+	if b.maxTotalBytes > 0 && b.totalBytes+max(size, 0) > b.maxTotalBytes {
+		return b.exhaust("total bytes", b.maxTotalBytes)
+	}
+	return b.checkFreeDisk(filepath.Dir(destPath), max(size, 0))
+}
+
+// checkFreeDisk fails once writing size more bytes into dir would leave less free disk
+// space than the budget keeps. Must be called with mu held.
+func (b *budget) checkFreeDisk(dir string, size int64) error {
+	if b.minFreeDisk <= 0 {
+		return nil
+	}
+	// Output directories are created as the run goes, so measure the filesystem they will be on
+	free, err := fsutil.FreeSpace(fsutil.ExistingAncestor(dir))
+	if err != nil {
+		if !b.diskUnknown {
+			b.diskUnknown = true
+			log.Printf("Warning: Cannot check free disk space, the free disk budget is not enforced: %v", err)
+		}
+		return nil
+	}
+	if free-size < b.minFreeDisk {
+		return b.exhaust("free disk", b.minFreeDisk)
+	}
+	return nil
+}
+
+// addBytes counts bytes written by a download that has so far written fileBytes, failing
+// as soon as the file or the run goes over its budget
+func (b *budget) addBytes(n, fileBytes int64) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.totalBytes += n
+	if b.maxFileBytes > 0 && fileBytes > b.maxFileBytes {
+		return &BudgetError{Budget: "file size", Limit: b.maxFileBytes, err: ErrFileTooLarge}
+	}
+	if b.maxTotalBytes > 0 && b.totalBytes > b.maxTotalBytes {
+		return b.exhaust("total bytes", b.maxTotalBytes)
+	}
+	return nil
+}
+
+// diskCheckInterval is how many bytes a download writes between free disk space checks
+const diskCheckInterval = 1 << 20
+
+// budgetWriter counts the bytes of one download into dir against the budget, and checks
+// the free disk space as the download goes, since the size of a file is not always known
+// before it starts
+type budgetWriter struct {
+	budget    *budget
+	dir       string
+	written   int64
+	unchecked int64 // Bytes written since the last free disk space check
+}
+
+func (w *budgetWriter) Write(p []byte) (int, error) {
+	w.written += int64(len(p))
+	if err := w.budget.addBytes(int64(len(p)), w.written); err != nil {
+		return 0, err
+	}
+
+	w.unchecked += int64(len(p))
+	if w.unchecked >= diskCheckInterval {
+		w.unchecked = 0
+		w.budget.mu.Lock()
+		err := w.budget.checkFreeDisk(w.dir, int64(len(p)))
+		w.budget.mu.Unlock()
+		if err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// BudgetErr returns the error of the first run budget that ran out, or nil while all
+// budgets have room left
+func (f *JamFetcher) BudgetErr() error {
+	f.budget.mu.Lock()
+	defer f.budget.mu.Unlock()
+
+	if f.budget.exhausted == nil {
+		return nil
+	}
+	return f.budget.exhausted
+}
@@ -0,0 +1,20 @@
+package fetcher
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"Itchalyser/config"
+)
+
+func TestCheckFreeDiskMissingDir(t *testing.T) {
+	// No disk has this much free space, so the check must fail rather than pass because
+	// the output directory does not exist yet
+	b := newBudget(config.Config{MinFreeDisk: 1 << 62})
+	dest := filepath.Join(t.TempDir(), "media", "123", "cover.png")
+
+	if err := b.checkDownload(1024, dest); !errors.Is(err, ErrBudgetExhausted) {
+		t.Errorf("checkDownload() = %v, want %v", err, ErrBudgetExhausted)
+	}
+}
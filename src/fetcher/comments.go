@@ -0,0 +1,231 @@
+package fetcher
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// maxCommentPages guards against following a broken comment pager forever
+const maxCommentPages = 500
+
+// Layout of the absolute date itch.io puts in a post's title attribute (UTC)
+const postDateLayout = "2006-01-02 15:04:05"
+
+var postIDRe = regexp.MustCompile(`post-(\d+)`)
+
+// postData is the JSON itch.io attaches to a post in its data-post attribute
+type postData struct {
+	ID       int `json:"id"`
+	ParentID int `json:"parent_id"`
+}
+
+// collectComments gathers the comments of a rate page and every following comment page,
+// recording in sources where the first page's comments were found. If a comment page
+// fails, the comments collected so far are returned with the error.
+func (f *JamFetcher) collectComments(ctx context.Context, pageURL string, doc *goquery.Document, sources fieldSources) ([]Comment, error) {
+	var comments []Comment
+	seen := make(map[int]bool)
+	visited := map[string]bool{pageURL: true}
+
+	for page := 0; page < maxCommentPages; page++ {
+		// Each page's comments come from its structured data if it has any
+		pageComments, source := structuredComments(doc), SourceJSONLD
+		if len(pageComments) == 0 {
+			pageComments, source = parseComments(doc), SourceSelector
+		}
+		if page == 0 && len(pageComments) > 0 {
+			sources["comments"] = source
+		}
+
+		for _, comment := range pageComments {
+			if comment.ID != 0 && seen[comment.ID] {
+				continue
+			}
+			seen[comment.ID] = true
+			// Absolute author URLs match the developers' profile URLs
+			comment.AuthorURL = f.ResolveURL(comment.AuthorURL)
+			comments = append(comments, comment)
+		}
+
+		next := doc.Find(".community_post_list_widget a.next_page, .pager a.next_page, a.next_page").First().AttrOr("href", "")
+		if next == "" {
+			break
+		}
+		pageURL = resolveReference(pageURL, next)
+		if visited[pageURL] {
+			break
+		}
+		visited[pageURL] = true
+
+		var err error
+		doc, err = f.fetchHTMLDoc(ctx, pageURL)
+		if err != nil {
+			return comments, fmt.Errorf("comment page %s: %w", pageURL, err)
+		}
+	}
+
+	return comments, nil
+}
+
+// structuredComments extracts the comments a page lists in its JSON-LD, under the
+// game's "comment" property, including replies nested in their parent's
+func structuredComments(doc *goquery.Document) []Comment {
+	ldGame := findJSONLD(jsonLDObjects(doc), "Product", "VideoGame", "SoftwareApplication")
+	if ldGame == nil {
+		return nil
+	}
+
+	var comments []Comment
+	var collect func(value any, parentID int)
+	collect = func(value any, parentID int) {
+		items, ok := value.([]any)
+		if !ok {
+			items = []any{value}
+		}
+		for _, item := range items {
+			object, ok := item.(map[string]any)
+			if !ok {
+				continue
+			}
+
+			author, _ := object["author"].(map[string]any)
+			comment := Comment{
+				ParentID:  parentID,
+				Author:    firstField(author, "name"),
+				AuthorURL: firstField(author, "url"),
+				Content:   firstField(object, "text"),
+				Timestamp: parseStructuredDate(firstField(object, "dateCreated")),
+			}
+			if comment.Author == "" {
+				comment.Author = stringValue(object["author"])
+			}
+			comment.ID, _ = strconv.Atoi(firstField(object, "identifier"))
+			if comment.ID == 0 {
+				if matches := postIDRe.FindStringSubmatch(firstField(object, "@id", "url")); len(matches) >= 2 {
+					comment.ID, _ = strconv.Atoi(matches[1])
+				}
+			}
+			if upvotes := firstField(object, "upvoteCount"); upvotes != "" {
+				comment.Ratings = map[string]int{"upvotes": parseInt(upvotes)}
+			}
+
+			comments = append(comments, comment)
+			collect(object["comment"], comment.ID)
+		}
+	}
+	collect(ldGame["comment"], 0)
+
+	return comments
+}
+
+// parseStructuredDate returns an ISO date from structured data as an RFC 3339 timestamp
+func parseStructuredDate(value string) string {
+	date, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		if date, err = time.Parse(postDateLayout, value); err != nil {
+			return ""
+		}
+	}
+	return date.UTC().Format(time.RFC3339)
+}
+
+// parseComments extracts every post on a page, including threaded replies
+func parseComments(doc *goquery.Document) []Comment {
+	var comments []Comment
+
+	doc.Find(".community_post").Each(func(i int, s *goquery.Selection) {
+		author := ownFind(s, ".post_author a").First()
+		authorName := strings.TrimSpace(author.Text())
+		if authorName == "" {
+			authorName = strings.TrimSpace(ownFind(s, ".post_author").First().Text())
+		}
+
+		comment := Comment{
+			Author:      authorName,
+			AuthorURL:   author.AttrOr("href", ""),
+			Content:     strings.TrimSpace(ownFind(s, ".post_body").First().Text()),
+			Timestamp:   parsePostDate(ownFind(s, ".post_date").First()),
+			IsDeveloper: isDeveloperPost(s),
+		}
+
+		// Post IDs come from the data-post JSON, or failing that the element ID
+		var data postData
+		if raw, ok := s.Attr("data-post"); ok && json.Unmarshal([]byte(raw), &data) == nil {
+			comment.ID = data.ID
+			comment.ParentID = data.ParentID
+		}
+		if comment.ID == 0 {
+			if matches := postIDRe.FindStringSubmatch(s.AttrOr("id", "")); len(matches) >= 2 {
+				comment.ID, _ = strconv.Atoi(matches[1])
+			}
+		}
+
+		// Replies are nested inside their parent post
+		if comment.ParentID == 0 {
+			if parent := s.ParentsFiltered(".community_post").First(); parent.Length() > 0 {
+				if matches := postIDRe.FindStringSubmatch(parent.AttrOr("id", "")); len(matches) >= 2 {
+					comment.ParentID, _ = strconv.Atoi(matches[1])
+				}
+			}
+		}
+
+		// Try to extract upvotes
+		upvotesText := ownFind(s, ".vote_button_count").First().Text()
+		if upvotesText != "" {
+			comment.Ratings = map[string]int{"upvotes": parseInt(upvotesText)}
+		}
+
+		comments = append(comments, comment)
+	})
+
+	return comments
+}
+
+// ownFind finds elements inside a post that do not belong to one of its nested replies
+func ownFind(post *goquery.Selection, selector string) *goquery.Selection {
+	return post.Find(selector).FilterFunction(func(i int, s *goquery.Selection) bool {
+		return s.Closest(".community_post").IsSelection(post)
+	})
+}
+
+// parsePostDate returns a post's date as an RFC 3339 timestamp, or "" if only a relative date is shown
+func parsePostDate(s *goquery.Selection) string {
+	title := strings.TrimSpace(s.AttrOr("title", ""))
+	if title == "" {
+		return ""
+	}
+
+	date, err := time.Parse(postDateLayout, title)
+	if err != nil {
+		// Some pages already use ISO dates
+		if date, err = time.Parse(time.RFC3339, title); err != nil {
+			return ""
+		}
+	}
+
+	return date.UTC().Format(time.RFC3339)
+}
+
+// isDeveloperPost reports whether itch.io marks a post as written by the game's developer
+func isDeveloperPost(s *goquery.Selection) bool {
+	if s.HasClass("by_owner") || s.HasClass("is_owner") {
+		return true
+	}
+
+	developer := false
+	ownFind(s, ".post_header .tag, .post_header .author_badge, .post_author .tag").Each(func(i int, badge *goquery.Selection) {
+		text := strings.ToLower(badge.Text())
+		if strings.Contains(text, "developer") || strings.Contains(text, "owner") {
+			developer = true
+		}
+	})
+
+	return developer
+}
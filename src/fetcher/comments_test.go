@@ -0,0 +1,127 @@
+package fetcher
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// commentPages is a rate page whose comments continue on a second page. The reply to
+// post 1 is nested in it on the first page and shown again on the second, and post 3
+// is a reply to post 1 that only says so in its data-post JSON.
+var commentPages = map[string]string{
+	"1": `<div class="community_post_list_widget">
+<div class="community_post" id="post-1" data-post='{"id":1}'>
+  <div class="post_header"><span class="post_author"><a href="/profile/bob">bob</a></span>
+    <span class="post_date" title="2025-03-02 10:00:00">1 day ago</span></div>
+  <div class="post_body">Great game</div>
+  <span class="vote_button_count">3</span>
+  <div class="community_post_replies">
+    <div class="community_post by_owner" id="post-2">
+      <div class="post_header"><span class="post_author"><a href="https://ann.itch.io">ann</a></span>
+        <span class="post_date" title="2025-03-02 11:30:00">1 day ago</span></div>
+      <div class="post_body">Thanks!</div>
+    </div>
+  </div>
+</div>
+<div class="pager"><a class="next_page" href="?page=2">Next page</a></div>
+</div>`,
+	"2": `<div class="community_post_list_widget">
+<div class="community_post by_owner" id="post-2">
+  <div class="post_header"><span class="post_author"><a href="https://ann.itch.io">ann</a></span>
+    <span class="post_date" title="2025-03-02 11:30:00">1 day ago</span></div>
+  <div class="post_body">Thanks!</div>
+</div>
+<div class="community_post" id="post-3" data-post='{"id":3,"parent_id":1}'>
+  <div class="post_header"><span class="post_author">carol</span>
+    <span class="post_date" title="2025-03-03T12:00:00+02:00">today</span></div>
+  <div class="post_body">Agreed</div>
+</div>
+<div class="community_post" id="post-4">
+  <div class="post_header"><span class="post_author"><a href="/profile/dan">dan</a></span>
+    <span class="post_date">just now</span></div>
+  <div class="post_body">Nice</div>
+</div>
+</div>`,
+}
+
+func TestCollectComments(t *testing.T) {
+	var requests [3]atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page := r.URL.Query().Get("page")
+		if page == "" {
+			page = "1"
+		}
+		body, ok := commentPages[page]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		requests[page[0]-'0'].Add(1)
+		fmt.Fprintf(w, "<html><body>%s</body></html>", body)
+	}))
+	defer server.Close()
+
+	f := newTestFetcher(t, server.URL)
+	pageURL := server.URL + "/jam/fixture-jam/rate/101"
+	doc, err := f.fetchHTMLDoc(context.Background(), pageURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sources := fieldSources{}
+	comments, err := f.collectComments(context.Background(), pageURL, doc, sources)
+	if err != nil {
+		t.Fatalf("collectComments: %v", err)
+	}
+
+	want := []Comment{
+		{ID: 1, Author: "bob", AuthorURL: server.URL + "/profile/bob", Content: "Great game", Timestamp: "2025-03-02T10:00:00Z", Ratings: map[string]int{"upvotes": 3}},
+		{ID: 2, ParentID: 1, Author: "ann", AuthorURL: "https://ann.itch.io", IsDeveloper: true, Content: "Thanks!", Timestamp: "2025-03-02T11:30:00Z"},
+		{ID: 3, ParentID: 1, Author: "carol", Content: "Agreed", Timestamp: "2025-03-03T10:00:00Z"},
+		{ID: 4, Author: "dan", AuthorURL: server.URL + "/profile/dan", Content: "Nice"},
+	}
+	if !reflect.DeepEqual(comments, want) {
+		t.Errorf("comments =\n%+v\nwant\n%+v", comments, want)
+	}
+	if sources["comments"] != SourceSelector {
+		t.Errorf("comments source = %q, want %q", sources["comments"], SourceSelector)
+	}
+	for page := 1; page <= 2; page++ {
+		if got := requests[page].Load(); got != 1 {
+			t.Errorf("page %d requests = %d, want 1", page, got)
+		}
+	}
+}
+
+func TestParsePostDate(t *testing.T) {
+	tests := []struct {
+		name string
+		html string
+		want string
+	}{
+		{"itch.io layout in UTC", `<span title="2025-03-02 10:00:00">1 day ago</span>`, "2025-03-02T10:00:00Z"},
+		{"ISO date with offset", `<span title="2025-03-03T12:00:00+02:00">today</span>`, "2025-03-03T10:00:00Z"},
+		{"relative date only", `<span>just now</span>`, ""},
+		{"unparseable title", `<span title="yesterday">yesterday</span>`, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := goquery.NewDocumentFromReader(strings.NewReader(tt.html))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := parsePostDate(doc.Find("span")); got != tt.want {
+				t.Errorf("parsePostDate = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package fetcher
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Kinds of failure, wrapped by the errors the fetcher returns so callers can tell them
+// apart with errors.Is
+var (
+	// ErrNotFound means the page or file does not exist (404 or 410), usually because
+	// the game or jam was deleted. Retrying does not help.
+	ErrNotFound = errors.New("not found")
+
+	// ErrRateLimited means itch.io kept answering 429 after every retry
+	ErrRateLimited = errors.New("rate limited")
+
+	// ErrForbidden means the request was refused (401 or 403) or needs a logged-in
+	// session, such as a private jam or a paid upload
+	ErrForbidden = errors.New("forbidden")
+
+	// ErrTransient means a network failure, timeout or server error that may succeed
+	// when tried again later
+	ErrTransient = errors.New("transient failure")
+
+	// ErrParse means a response could not be read as expected, usually because itch.io
+	// changed its page layout or JSON
+	ErrParse = errors.New("unexpected response")
+)
+
+// StatusError describes a response with an unexpected status code. It wraps the kind
+// of failure the status stands for, if any.
+type StatusError struct {
+	URL        string
+	StatusCode int
+	kind       error
+}
+
+// newStatusError creates the error for a response with an unexpected status code
+func newStatusError(url string, statusCode int) *StatusError {
+	return &StatusError{URL: url, StatusCode: statusCode, kind: statusKind(statusCode)}
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("request to %s failed, status code: %d", e.URL, e.StatusCode)
+}
+
+func (e *StatusError) Unwrap() error {
+	return e.kind
+}
+
+// statusKind returns the kind of failure a response status stands for, or nil for
+// statuses that fit none
+func statusKind(code int) error {
+	switch {
+	case code == http.StatusNotFound || code == http.StatusGone:
+		return ErrNotFound
+	case code == http.StatusTooManyRequests:
+		return ErrRateLimited
+	case code == http.StatusUnauthorized || code == http.StatusForbidden:
+		return ErrForbidden
+	case code == http.StatusRequestTimeout || code >= 500:
+		return ErrTransient
+	}
+	return nil
+}
+
+// NetworkError describes a request that failed without a response, or whose
+// response was cut short. It wraps ErrTransient and the underlying error.
+type NetworkError struct {
+	URL string
+	err error
+}
+
+func (e *NetworkError) Error() string {
+	return fmt.Sprintf("request to %s failed: %v", e.URL, e.err)
+}
+
+func (e *NetworkError) Unwrap() []error {
+	return []error{ErrTransient, e.err}
+}
+
+// ParseError describes a response that could not be read as expected. It wraps
+// ErrParse and the underlying error.
+type ParseError struct {
+	URL string
+	err error
+}
+
+// newParseError creates a parse error for url with a formatted description
+func newParseError(url, format string, args ...any) *ParseError {
+	return &ParseError{URL: url, err: fmt.Errorf(format, args...)}
+}
+
+func (e *ParseError) Error() string {
+	if e.URL == "" {
+		return e.err.Error()
+	}
+	return fmt.Sprintf("%s: %v", e.URL, e.err)
+}
+
+func (e *ParseError) Unwrap() []error {
+	return []error{ErrParse, e.err}
+}
@@ -1,124 +1,172 @@
 package fetcher
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"log"
 	"net/http"
-	"net/url"
-	"os"
+	"net/http/cookiejar"
+	neturl "net/url"
 	"path/filepath"
 	"regexp"
 	"strings"
 	"time"
 
 	"github.com/PuerkitoBio/goquery"
+
+	"Itchalyser/config"
+	"Itchalyser/fsutil"
 )
 
 // JamFetcher handles fetching data from itch.io
 type JamFetcher struct {
-	client     *http.Client
-	userAgent  string
-	requestDelay time.Duration
+	client      *http.Client
+	fileClient  *http.Client  // No overall timeout, for game files that take minutes to download
+	readTimeout time.Duration // Longest a download may go without receiving any data
+	userAgent   string
+	baseURL     string
+	baseHost    string
+	jamURLRe    *regexp.Regexp
+	limiter     *rateLimiter // Shared by all requests to itch.io itself
+	cdnLimiter  *rateLimiter // Shared by all downloads from other hosts
+	retry       retryPolicy
+	apiURL      string
+	apiHost     string
+	apiKey      string // Sent only to apiHost, never logged
+	budget      *budget
+	stats       RequestStats
 }
 
-// NewFetcher creates a new JamFetcher with the given user agent and delay
-func NewFetcher(userAgent string, delayMS int) *JamFetcher {
-	if delayMS <= 0 {
-		delayMS = 500 // Default delay of 0.5 seconds
+// NewFetcher creates a new JamFetcher from the given configuration
+func NewFetcher(cfg config.Config) *JamFetcher {
+	rps := cfg.RequestsPerSecond
+	if rps <= 0 {
+		delayMS := cfg.RequestDelay
+		if delayMS <= 0 {
+			delayMS = 500 // Default delay of 0.5 seconds
+		}
+		rps = 1000 / float64(delayMS)
+	}
+
+	cdnRPS := cfg.CDNRequestsPerSecond
+	if cdnRPS <= 0 {
+		cdnRPS = rps
+	}
+
+	jar, _ := cookiejar.New(nil)
+
+	apiURL := strings.TrimRight(cfg.APIURL, "/")
+	if apiURL == "" {
+		apiURL = config.DefaultAPIURL
+	}
+
+	baseURL := strings.TrimRight(cfg.BaseURL, "/")
+	if baseURL == "" {
+		baseURL = config.DefaultBaseURL
 	}
 	
 	return &JamFetcher{
 		client: &http.Client{
 			Timeout: 30 * time.Second,
+			Jar:     jar, // Keeps the session cookie the download flow's CSRF token is tied to
 		},
-		userAgent:    userAgent,
-		requestDelay: time.Duration(delayMS) * time.Millisecond,
+		fileClient: &http.Client{
+			Transport: &http.Transport{
+				Proxy:                 http.ProxyFromEnvironment,
+				ResponseHeaderTimeout: 30 * time.Second,
+			},
+			Jar: jar,
+		},
+		readTimeout: fileReadTimeout,
+		userAgent:   cfg.UserAgent,
+		baseURL:     baseURL,
+		baseHost:    hostOf(baseURL),
+		jamURLRe:    regexp.MustCompile(regexp.QuoteMeta(hostOf(baseURL)) + `/jam/([^/?#]+)`),
+		limiter:     newRateLimiter(rps, cfg.Burst),
+		cdnLimiter:  newRateLimiter(cdnRPS, cfg.CDNBurst),
+		retry:       newRetryPolicy(cfg.MaxAttempts, cfg.RetryBaseDelay, cfg.RetryMaxDelay),
+		apiURL:      apiURL,
+		apiHost:     hostOf(apiURL),
+		apiKey:      cfg.APIKey,
+		budget:      newBudget(cfg),
 	}
 }
 
-// ExtractJamID extracts the jam ID from a jam URL
-func ExtractJamID(jamURL string) (string, error) {
-	// Handle URLs like https://itch.io/jam/brackeys-13
-	re := regexp.MustCompile(`itch\.io/jam/([^/]+)`)
-	matches := re.FindStringSubmatch(jamURL)
-	if len(matches) >= 2 {
-		return matches[1], nil
-	}
-
-	// Try to extract from different URL format or from the page content
-	doc, err := fetchHTMLDoc(jamURL)
-	if err != nil {
-		return "", err
-	}
-
-	// Look for randomizer link which contains the jam ID
-	randomizerLink := doc.Find("a.randomizer_link").AttrOr("href", "")
-	if randomizerLink != "" {
-		re = regexp.MustCompile(`jam_id=(\d+)`)
-		matches = re.FindStringSubmatch(randomizerLink)
-		if len(matches) >= 2 {
-			return matches[1], nil
-		}
-	}
-
-	return "", errors.New("could not extract jam ID from URL")
+// BaseURL returns the base URL all requests are made against
+func (f *JamFetcher) BaseURL() string {
+	return f.baseURL
 }
 
 // FetchJamEntries fetches entries from the JSON endpoint
-func (f *JamFetcher) FetchJamEntries(jamID string) (*JamEntriesResponse, error) {
-	url := fmt.Sprintf("https://itch.io/jam/%s/entries.json", jamID)
+func (f *JamFetcher) FetchJamEntries(ctx context.Context, jamID string) (*JamEntriesResponse, error) {
+	url := fmt.Sprintf("%s/jam/%s/entries.json", f.baseURL, jamID)
 	
-	time.Sleep(f.requestDelay) // Respect rate limiting
-	
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return nil, err
-	}
-	
-	req.Header.Set("User-Agent", f.userAgent)
-	
-	resp, err := f.client.Do(req)
+	resp, err := f.get(ctx, url)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 	
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("failed to fetch jam entries, status code: %d", resp.StatusCode)
+		return nil, fmt.Errorf("failed to fetch jam entries: %w", newStatusError(url, resp.StatusCode))
 	}
 	
 	var entriesResponse JamEntriesResponse
 	if err := json.NewDecoder(resp.Body).Decode(&entriesResponse); err != nil {
-		return nil, err
+		return nil, newParseError(url, "failed to decode jam entries: %w", err)
 	}
 	
 	return &entriesResponse, nil
 }
 
-// FetchJamMetadata fetches metadata about the jam
-func (f *JamFetcher) FetchJamMetadata(jamID string) (*JamMetadata, error) {
-	url := fmt.Sprintf("https://itch.io/jam/%s", jamID)
-	
-	time.Sleep(f.requestDelay) // Respect rate limiting
-	
-	doc, err := f.fetchHTMLDoc(url)
-	if err != nil {
-		return nil, err
+// JamEntries returns the entries of a jam, reusing those ResolveJam downloaded to look
+// up its slug
+func (f *JamFetcher) JamEntries(ctx context.Context, jam JamRef) (*JamEntriesResponse, error) {
+	if jam.entries != nil {
+		return jam.entries, nil
+	}
+	return f.FetchJamEntries(ctx, jam.ID)
+}
+
+// FetchJamMetadata fetches metadata about the jam, reusing its page if ResolveJam
+// fetched it already
+func (f *JamFetcher) FetchJamMetadata(ctx context.Context, jam JamRef) (*JamMetadata, error) {
+	url := fmt.Sprintf("%s/jam/%s", f.baseURL, jam.Slug)
+	
+	doc := jam.page
+	if doc == nil {
+		var err error
+		if doc, err = f.fetchHTMLDoc(ctx, url); err != nil {
+			return nil, err
+		}
 	}
 	
+	ldJam := findJSONLD(jsonLDObjects(doc), "Event")
+	initJam := findInitObject(initObjects(doc), "jam")
+	sources := fieldSources{}
+	
+	// Structured data comes first; CSS selectors are only a fallback
 	metadata := &JamMetadata{
-		ID:    jamID,
-		Title: strings.TrimSpace(doc.Find(".jam_title_header").Text()),
+		ID: jam.Slug,
+		Title: sources.pick("title",
+			sourced{SourceJSONLD, stringField(ldJam, "name")},
+			sourced{SourceInitJSON, stringField(initJam, "title")},
+			sourced{SourceSelector, doc.Find(".jam_title_header").Text()}),
+		FieldSources: sources,
 	}
 	
-	// Try to extract internal ID
-	internalID, err := extractInternalIDFromPage(doc)
-	if err != nil {
-		return nil, err
-	}
-	if internalID != "" {
+	// A resolved jam already carries its numeric ID; otherwise read it from the page
+	metadata.InternalID = jam.ID
+	if metadata.InternalID == "" {
+		internalID, err := extractInternalIDFromPage(doc, initJam, sources)
+		if err != nil {
+			return nil, err
+		}
 		metadata.InternalID = internalID
 	}
 	
@@ -130,6 +178,9 @@ func (f *JamFetcher) FetchJamMetadata(jamID string) (*JamMetadata, error) {
 		}
 		metadata.Hosts = append(metadata.Hosts, host)
 	})
+	if len(metadata.Hosts) > 0 {
+		sources["hosts"] = SourceSelector
+	}
 	
 	// Extract stats
 	doc.Find(".stat_box").Each(func(i int, s *goquery.Selection) {
@@ -138,60 +189,83 @@ func (f *JamFetcher) FetchJamMetadata(jamID string) (*JamMetadata, error) {
 		
 		switch strings.ToLower(label) {
 		case "entries":
-			metadata.SubmissionCount = value
+			metadata.SubmissionCount = sources.pick("submission_count", sourced{SourceSelector, value})
 		case "ratings":
-			metadata.RatingCount = value
+			metadata.RatingCount = sources.pick("rating_count", sourced{SourceSelector, value})
 		case "comments":
-			metadata.CommentsCount = value
+			metadata.CommentsCount = sources.pick("comments_count", sourced{SourceSelector, value})
 		}
 	})
 	
-	// Extract dates
+	// Extract dates shown on the page as a fallback for the structured ones
+	var startDate, endDate, submissionDate string
 	doc.Find(".jam_details_widget .line").Each(func(i int, s *goquery.Selection) {
 		label := strings.TrimSpace(s.Find(".label").Text())
 		value := strings.TrimSpace(s.Find(".date_countdown").Text())
 		
 		switch {
 		case strings.Contains(strings.ToLower(label), "start"):
-			metadata.StartDate = value
+			startDate = value
 		case strings.Contains(strings.ToLower(label), "end"):
-			metadata.EndDate = value
+			endDate = value
 		case strings.Contains(strings.ToLower(label), "submission"):
-			metadata.SubmissionDate = value
+			submissionDate = value
 		}
 	})
+	metadata.StartDate = sources.pick("start_date",
+		sourced{SourceJSONLD, stringField(ldJam, "startDate")},
+		sourced{SourceInitJSON, stringField(initJam, "start_date")},
+		sourced{SourceSelector, startDate})
+	metadata.EndDate = sources.pick("end_date",
+		sourced{SourceJSONLD, stringField(ldJam, "endDate")},
+		sourced{SourceInitJSON, stringField(initJam, "end_date")},
+		sourced{SourceSelector, endDate})
+	metadata.SubmissionDate = sources.pick("submission_date",
+		sourced{SourceInitJSON, stringField(initJam, "submission_end_date")},
+		sourced{SourceSelector, submissionDate})
 	
 	// Extract theme
-	metadata.Theme = strings.TrimSpace(doc.Find(".jam_theme_display").Text())
+	metadata.Theme = sources.pick("theme",
+		sourced{SourceInitJSON, stringField(initJam, "theme")},
+		sourced{SourceSelector, doc.Find(".jam_theme_display").Text()})
 	
 	// Extract cover image
-	coverImgSrc := doc.Find(".jam_cover").AttrOr("src", "")
-	if coverImgSrc != "" {
-		metadata.CoverImageURL = coverImgSrc
-	}
+	metadata.CoverImageURL = sources.pick("cover_image_url",
+		sourced{SourceJSONLD, stringField(ldJam, "image")},
+		sourced{SourceInitJSON, stringField(initJam, "cover")},
+		sourced{SourceSelector, doc.Find(".jam_cover").AttrOr("src", "")})
 	
 	return metadata, nil
 }
 
-// extractInternalIDFromPage tries to extract the internal ID from the page content
-func extractInternalIDFromPage(doc *goquery.Document) (string, error) {
+// jamIDRe matches an explicit jam ID in script text
+var jamIDRe = regexp.MustCompile(`"jam_id"\s*:\s*(\d+)`)
+
+// extractInternalIDFromPage extracts the jam's numeric ID, preferring the jam object
+// in the page's initialisation data over pattern matches on raw script text
+func extractInternalIDFromPage(doc *goquery.Document, initJam map[string]any, sources fieldSources) (string, error) {
+	if id := stringField(initJam, "id"); id != "" {
+		sources["internal_id"] = SourceInitJSON
+		return id, nil
+	}
+
 	script := doc.Find("script").Text()
-	re := regexp.MustCompile(`"id":(\d+)`)
-	matches := re.FindStringSubmatch(script)
-	if len(matches) >= 2 {
+	if matches := jamIDRe.FindStringSubmatch(script); len(matches) >= 2 {
+		sources["internal_id"] = SourceScript
 		return matches[1], nil
 	}
-	
-	return "", errors.New("could not extract internal ID from page")
+
+	// Any other ID in the scripts may belong to another object, so it is not guessed at
+	return "", newParseError("", "could not extract internal ID from page")
 }
 
-// FetchGameDetails fetches detailed information about a game submission
-func (f *JamFetcher) FetchGameDetails(jamID, gameID string) (*GameSubmission, error) {
-	url := fmt.Sprintf("https://itch.io/jam/%s/rate/%s", jamID, gameID)
-	
-	time.Sleep(f.requestDelay) // Respect rate limiting
+// FetchGameDetails fetches detailed information about a game submission. If a later
+// comment page fails, the game is returned along with the error, keeping the comments
+// fetched so far.
+func (f *JamFetcher) FetchGameDetails(ctx context.Context, jamID, gameID string) (*GameSubmission, error) {
+	url := fmt.Sprintf("%s/jam/%s/rate/%s", f.baseURL, jamID, gameID)
 	
-	doc, err := f.fetchHTMLDoc(url)
+	doc, err := f.fetchHTMLDoc(ctx, url)
 	if err != nil {
 		return nil, err
 	}
@@ -200,161 +274,314 @@ func (f *JamFetcher) FetchGameDetails(jamID, gameID string) (*GameSubmission, er
 		ID: gameID,
 	}
 	
-	// Extract description
-	game.Description = strings.TrimSpace(doc.Find(".formatted_description").Text())
-	
-	// Extract screenshots
-	doc.Find("[data-screenshot_id]").Each(func(i int, s *goquery.Selection) {
-		screenshotURL := s.AttrOr("data-screenshot_src", "")
-		if screenshotURL != "" {
-			game.Screenshots = append(game.Screenshots, screenshotURL)
-		}
-	})
-	
-	// Extract downloads
-	doc.Find(".upload_list_widget .upload").Each(func(i int, s *goquery.Selection) {
-		download := Download{
-			Filename: strings.TrimSpace(s.Find(".upload_name").Text()),
-			Size:     strings.TrimSpace(s.Find(".file_size").Text()),
+	// Extract description, preferring structured data
+	ldGame := findJSONLD(jsonLDObjects(doc), "Product", "VideoGame", "SoftwareApplication")
+	inits := initObjects(doc)
+	sources := fieldSources{}
+	game.FieldSources = sources
+	game.Description = sources.pick("description",
+		sourced{SourceJSONLD, stringField(ldGame, "description")},
+		sourced{SourceSelector, doc.Find(".formatted_description").Text()})
+	
+	// Extract screenshots, preferring structured data
+	if screenshots := stringList(ldGame["screenshot"]); len(screenshots) > 0 {
+		for _, screenshotURL := range screenshots {
+			game.Screenshots = append(game.Screenshots, f.ResolveURL(screenshotURL))
 		}
-		
-		// Extract platforms
-		s.Find(".download_platforms .platform_tag").Each(func(j int, p *goquery.Selection) {
-			platform := strings.TrimSpace(p.Text())
-			download.Platforms = append(download.Platforms, platform)
+		sources["screenshots"] = SourceJSONLD
+	} else {
+		doc.Find("[data-screenshot_id]").Each(func(i int, s *goquery.Selection) {
+			screenshotURL := s.AttrOr("data-screenshot_src", "")
+			if screenshotURL != "" {
+				game.Screenshots = append(game.Screenshots, screenshotURL)
+			}
 		})
-		
-		// Extract upload date
-		uploadDateText := strings.TrimSpace(s.Find(".upload_date").Text())
-		download.UploadDate = uploadDateText
-		
-		game.Downloads = append(game.Downloads, download)
-	})
+		if len(game.Screenshots) > 0 {
+			sources["screenshots"] = SourceSelector
+		}
+	}
 	
-	// Extract criteria responses
-	doc.Find(".field_responses p").Each(func(i int, s *goquery.Selection) {
-		questionText := strings.TrimSpace(s.Find("strong").Text())
-		// Remove the question and any HTML tags for the answer
-		s.Find("strong").Remove()
-		answerText := strings.TrimSpace(s.Text())
-		
-		if questionText != "" && answerText != "" {
-			if game.CriteriaResponses == nil {
-				game.CriteriaResponses = make(map[string]string)
-			}
-			
-			// Convert question to a key
-			key := strings.ToLower(questionText)
-			key = strings.Replace(key, "?", "", -1)
-			key = strings.Replace(key, " ", "_", -1)
+	// Extract downloads
+	game.Downloads = parseUploads(doc, "")
+	
+	// Extract criteria responses, preferring the page's initialisation data
+	if responses := initFieldResponses(inits); len(responses) > 0 {
+		game.CriteriaResponses = responses
+		sources["criteria_responses"] = SourceInitJSON
+	} else {
+		doc.Find(".field_responses p").Each(func(i int, s *goquery.Selection) {
+			questionText := strings.TrimSpace(s.Find("strong").Text())
+			// Remove the question and any HTML tags for the answer
+			s.Find("strong").Remove()
+			answerText := strings.TrimSpace(s.Text())
 			
-			game.CriteriaResponses[key] = answerText
+			if questionText != "" && answerText != "" {
+				if game.CriteriaResponses == nil {
+					game.CriteriaResponses = make(map[string]string)
+				}
+				game.CriteriaResponses[criteriaResponseKey(questionText)] = answerText
+			}
+		})
+		if len(game.CriteriaResponses) > 0 {
+			sources["criteria_responses"] = SourceSelector
 		}
-	})
+	}
 	
-	// Extract comments
-	doc.Find(".community_post").Each(func(i int, s *goquery.Selection) {
-		comment := Comment{
-			Author:    strings.TrimSpace(s.Find(".post_author").Text()),
-			Content:   strings.TrimSpace(s.Find(".post_body").Text()),
-			Timestamp: strings.TrimSpace(s.Find(".post_date").Text()),
-		}
-		
-		// Try to extract upvotes
-		upvotesText := s.Find(".vote_button_count").Text()
-		if upvotesText != "" {
-			comment.Ratings = map[string]int{"upvotes": parseInt(upvotesText)}
-		}
-		
-		game.Comments = append(game.Comments, comment)
-	})
+	// Extract comments from every comment page, including replies
+	game.Comments, err = f.collectComments(ctx, url, doc, sources)
+	if err != nil {
+		return game, err
+	}
 	
 	return game, nil
 }
 
-// DownloadFile downloads a file from a URL to the specified path
-func (f *JamFetcher) DownloadFile(url, destPath string) error {
-	time.Sleep(f.requestDelay) // Respect rate limiting
-	
-	// Create directory if it doesn't exist
-	dir := filepath.Dir(destPath)
-	if err := os.MkdirAll(dir, 0755); err != nil {
-		return err
-	}
-	
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return err
+// criteriaResponseKey converts a submission question to the key of its answer
+func criteriaResponseKey(question string) string {
+	key := strings.ToLower(strings.TrimSpace(question))
+	key = strings.Replace(key, "?", "", -1)
+	key = strings.Replace(key, " ", "_", -1)
+	return key
+}
+
+// initFieldResponses returns the answers to the jam's submission questions from the
+// "field_responses" of the page's initialisation data, given either as a list of
+// question and answer objects or as an object of answers by question
+func initFieldResponses(objects []map[string]any) map[string]string {
+	responses := make(map[string]string)
+	for _, object := range objects {
+		switch fields := object["field_responses"].(type) {
+		case []any:
+			for _, item := range fields {
+				field, _ := item.(map[string]any)
+				question := firstField(field, "question", "title", "label")
+				answer := firstField(field, "answer", "response", "value")
+				if question != "" && answer != "" {
+					responses[criteriaResponseKey(question)] = answer
+				}
+			}
+		case map[string]any:
+			for question, value := range fields {
+				if answer := strings.TrimSpace(stringValue(value)); answer != "" {
+					responses[criteriaResponseKey(question)] = answer
+				}
+			}
+		}
+		if len(responses) > 0 {
+			return responses
+		}
 	}
+	return nil
+}
+
+// DownloadFile downloads a file from a URL to the specified path. The file is written
+// to a temporary file first and only renamed into place once it is complete, so an
+// interrupted or truncated download never leaves a partial file at destPath.
+func (f *JamFetcher) DownloadFile(ctx context.Context, url, destPath string) (*DownloadResult, error) {
+	return f.downloadTo(ctx, f.client, url, destPath)
+}
+
+// downloadTo streams a URL into destPath using the given client, see DownloadFile
+func (f *JamFetcher) downloadTo(ctx context.Context, client *http.Client, url, destPath string) (*DownloadResult, error) {
+	url = f.ResolveURL(url)
 	
-	req.Header.Set("User-Agent", f.userAgent)
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
 	
-	resp, err := f.client.Do(req)
+	resp, err := f.do(ctx, client, "GET", url, nil)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer resp.Body.Close()
 	
+	// Game files have no overall timeout, so a transfer that stalls is cancelled once
+	// no data has arrived for a while
+	idle := time.AfterFunc(f.readTimeout, func() { cancel(errReadTimeout) })
+	defer idle.Stop()
+	body := &idleReader{body: resp.Body, timer: idle, timeout: f.readTimeout}
+	
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("failed to download file, status code: %d", resp.StatusCode)
+		return nil, fmt.Errorf("failed to download file: %w", newStatusError(url, resp.StatusCode))
 	}
 	
-	file, err := os.Create(destPath)
-	if err != nil {
-		return err
+	// Refuse files the budgets have no room for before writing anything
+	if err := f.budget.checkDownload(resp.ContentLength, destPath); err != nil {
+		return nil, err
 	}
-	defer file.Close()
 	
-	_, err = io.Copy(file, resp.Body)
-	return err
-}
-
-// Helper to fetch HTML document
-func (f *JamFetcher) fetchHTMLDoc(url string) (*goquery.Document, error) {
-	req, err := http.NewRequest("GET", url, nil)
+	file, err := fsutil.CreateTemp(destPath)
 	if err != nil {
 		return nil, err
 	}
 	
-	req.Header.Set("User-Agent", f.userAgent)
-	
-	resp, err := f.client.Do(req)
+	// Bytes are counted before they are written, so a file without a Content-Length
+	// still stops at the budget
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(&budgetWriter{budget: f.budget, dir: filepath.Dir(destPath)}, file, hash), body)
+	f.countBytes(ctx, size)
+	if err != nil && errors.Is(context.Cause(ctx), errReadTimeout) {
+		err = &NetworkError{URL: url, err: fmt.Errorf("%w after %s", errReadTimeout, f.readTimeout)}
+	}
+	if err == nil && resp.ContentLength >= 0 && size != resp.ContentLength {
+		err = &NetworkError{URL: url, err: fmt.Errorf("incomplete download: got %d of %d bytes", size, resp.ContentLength)}
+	}
 	if err != nil {
+		fsutil.Discard(file)
 		return nil, err
 	}
-	defer resp.Body.Close()
-	
-	// fmt.Printf("Fetching HTML document: %s", url)
 	
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("failed to fetch page, status code: %d", resp.StatusCode)
+	if err := fsutil.Commit(file, destPath); err != nil {
+		return nil, err
 	}
 	
-	return goquery.NewDocumentFromReader(resp.Body)
+	return &DownloadResult{
+		URL:    url,
+		Path:   destPath,
+		Size:   size,
+		SHA256: hex.EncodeToString(hash.Sum(nil)),
+	}, nil
 }
 
-// Helper function for non-method contexts
-func fetchHTMLDoc(url string) (*goquery.Document, error) {
-	client := &http.Client{Timeout: 30 * time.Second}
-	
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return nil, err
+// fileReadTimeout is how long a download may go without receiving any data
+const fileReadTimeout = 30 * time.Second
+
+// errReadTimeout cancels a download whose body stopped sending data
+var errReadTimeout = errors.New("no data received")
+
+// idleReader reads a response body, pushing back its timer each time data arrives
+type idleReader struct {
+	body    io.Reader
+	timer   *time.Timer
+	timeout time.Duration
+}
+
+func (r *idleReader) Read(p []byte) (int, error) {
+	n, err := r.body.Read(p)
+	if n > 0 {
+		r.timer.Reset(r.timeout)
 	}
-	
-	req.Header.Set("User-Agent", "ItchJamScraper/1.0")
-	
-	resp, err := client.Do(req)
+	return n, err
+}
+
+// get performs a GET request through the shared rate limiter, retrying transport
+// errors and temporary server errors with exponential backoff. Responses with other
+// non-200 statuses are returned to the caller as they are.
+func (f *JamFetcher) get(ctx context.Context, url string) (*http.Response, error) {
+	return f.do(ctx, f.client, "GET", url, nil)
+}
+
+// postForm performs a form POST with the same rate limiting and retries as get
+func (f *JamFetcher) postForm(ctx context.Context, url string, form neturl.Values) (*http.Response, error) {
+	return f.do(ctx, f.client, "POST", url, form)
+}
+
+// do sends a request with an optional form body, see get
+func (f *JamFetcher) do(ctx context.Context, client *http.Client, method, url string, form neturl.Values) (*http.Response, error) {
+	var lastErr error
+
+	for attempt := 1; attempt <= f.retry.maxAttempts; attempt++ {
+		if err := f.budget.takeRequest(); err != nil {
+			return nil, err
+		}
+		f.countRequest(ctx, attempt)
+
+		var body io.Reader
+		if form != nil {
+			body = strings.NewReader(form.Encode())
+		}
+
+		req, err := http.NewRequestWithContext(ctx, method, url, body)
+		if err != nil {
+			return nil, err
+		}
+		
+		req.Header.Set("User-Agent", f.userAgent)
+		f.authorize(req)
+		if form != nil {
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			req.Header.Set("X-Requested-With", "XMLHttpRequest")
+		}
+		
+		limiter := f.limiterFor(req.URL.Host)
+		if err := limiter.Wait(ctx); err != nil { // Respect rate limiting
+			return nil, err
+		}
+		
+		resp, err := client.Do(req)
+		if err == nil && !isRetryableStatus(resp.StatusCode) {
+			return resp, nil
+		}
+		if ctx.Err() != nil {
+			// Cancelled while the request was in flight
+			if err == nil {
+				resp.Body.Close()
+			}
+			return nil, ctx.Err()
+		}
+
+		wait := f.retry.backoff(attempt)
+		if err != nil {
+			lastErr = &NetworkError{URL: url, err: err}
+		} else {
+			lastErr = newStatusError(url, resp.StatusCode)
+
+			if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable {
+				if retryAfter, ok := parseRetryAfter(resp.Header.Get("Retry-After")); ok {
+					wait = retryAfter
+				}
+			}
+			if resp.StatusCode == http.StatusTooManyRequests {
+				// Everyone sharing this limiter backs off, not just this request
+				limiter.SlowDown(wait, rateLimitCooldown)
+			}
+			resp.Body.Close()
+		}
+
+		if attempt < f.retry.maxAttempts {
+			log.Printf("Retrying %s in %s (attempt %d/%d): %v", url, wait.Round(time.Millisecond), attempt+1, f.retry.maxAttempts, lastErr)
+			if err := sleepContext(ctx, wait); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return nil, fmt.Errorf("giving up after %d attempts: %w", f.retry.maxAttempts, lastErr)
+}
+
+// EstimateRequestTime returns the minimum time the rate limiters need for the given
+// number of itch.io requests and CDN downloads, ignoring retries and transfer time
+func (f *JamFetcher) EstimateRequestTime(siteRequests, cdnRequests int) (site, cdn time.Duration) {
+	return f.limiter.estimate(siteRequests), f.cdnLimiter.estimate(cdnRequests)
+}
+
+// limiterFor returns the rate limiter for a host: itch.io and its subdomains share
+// one budget, everything else (image and file CDNs) shares another
+func (f *JamFetcher) limiterFor(host string) *rateLimiter {
+	if host == f.baseHost || strings.HasSuffix(host, "."+f.baseHost) {
+		return f.limiter
+	}
+	return f.cdnLimiter
+}
+
+// Helper to fetch HTML document
+func (f *JamFetcher) fetchHTMLDoc(ctx context.Context, url string) (*goquery.Document, error) {
+	resp, err := f.get(ctx, url)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 	
+	// fmt.Printf("Fetching HTML document: %s", url)
+	
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("failed to fetch page, status code: %d", resp.StatusCode)
+		return nil, fmt.Errorf("failed to fetch page: %w", newStatusError(url, resp.StatusCode))
 	}
 	
-	return goquery.NewDocumentFromReader(resp.Body)
+	doc, err := goquery.NewDocumentFromReader(resp.Body)
+	if err != nil {
+		return nil, &NetworkError{URL: url, err: err}
+	}
+	return doc, nil
 }
 
 // Helper function to parse int from string, returns 0 if parsing fails
@@ -368,9 +595,36 @@ func parseInt(s string) int {
 	return result
 }
 
+// ResolveURL turns a site-relative URL (such as "/jam/foo") into an absolute one on the base URL
+func (f *JamFetcher) ResolveURL(rawURL string) string {
+	if rawURL == "" || IsAbsoluteURL(rawURL) {
+		return rawURL
+	}
+	if strings.HasPrefix(rawURL, "//") {
+		u, err := neturl.Parse(f.baseURL)
+		if err == nil {
+			return u.Scheme + ":" + rawURL
+		}
+		return "https:" + rawURL
+	}
+	if !strings.HasPrefix(rawURL, "/") {
+		rawURL = "/" + rawURL
+	}
+	return f.baseURL + rawURL
+}
+
+// hostOf returns the host (and port) part of a URL, or the URL itself if it cannot be parsed
+func hostOf(rawURL string) string {
+	u, err := neturl.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return rawURL
+	}
+	return u.Host
+}
+
 // IsAbsoluteURL checks if a URL is absolute
 func IsAbsoluteURL(rawURL string) bool {
-	u, err := url.Parse(rawURL)
+	u, err := neturl.Parse(rawURL)
 	return err == nil && u.Scheme != "" && u.Host != ""
 }
 
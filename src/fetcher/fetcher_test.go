@@ -0,0 +1,205 @@
+package fetcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"Itchalyser/config"
+)
+
+// testConfig returns the configuration of a fetcher for a test server, which retries
+// quickly and is barely rate limited
+func testConfig(baseURL string) config.Config {
+	return config.Config{
+		BaseURL:           baseURL,
+		UserAgent:         "Itchalyser-test",
+		RequestsPerSecond: 1000,
+		Burst:             100,
+		MaxAttempts:       3,
+		RetryBaseDelay:    1,
+		RetryMaxDelay:     5,
+	}
+}
+
+// newTestFetcher creates a fetcher for a test server, see testConfig
+func newTestFetcher(t *testing.T, baseURL string) *JamFetcher {
+	t.Helper()
+	return NewFetcher(testConfig(baseURL))
+}
+
+func TestRetry(t *testing.T) {
+	tests := []struct {
+		name         string
+		statuses     []int // Status of each attempt; the last one repeats
+		wantAttempts int32
+		wantErr      error
+	}{
+		{"success", []int{200}, 1, nil},
+		{"not found is not retried", []int{404}, 1, nil},
+		{"server error then success", []int{500, 200}, 2, nil},
+		{"unavailable twice then success", []int{503, 503, 200}, 3, nil},
+		{"still failing", []int{502}, 3, ErrTransient},
+		{"still rate limited", []int{429}, 3, ErrRateLimited},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var attempts atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				n := int(attempts.Add(1))
+				w.WriteHeader(tt.statuses[min(n, len(tt.statuses))-1])
+			}))
+			defer server.Close()
+
+			resp, err := newTestFetcher(t, server.URL).get(context.Background(), server.URL+"/page")
+			if resp != nil {
+				resp.Body.Close()
+			}
+
+			if got := attempts.Load(); got != tt.wantAttempts {
+				t.Errorf("attempts = %d, want %d", got, tt.wantAttempts)
+			}
+			switch {
+			case tt.wantErr == nil && err != nil:
+				t.Errorf("unexpected error: %v", err)
+			case tt.wantErr != nil && !errors.Is(err, tt.wantErr):
+				t.Errorf("error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     int
+		retryAfter func() string
+		minWait    time.Duration
+	}{
+		{"429 in seconds", http.StatusTooManyRequests, func() string { return "1" }, time.Second},
+		{"503 in seconds", http.StatusServiceUnavailable, func() string { return "1" }, time.Second},
+		{"503 as a date", http.StatusServiceUnavailable, func() string {
+			// HTTP dates have whole seconds, so this is one to two seconds away
+			return time.Now().Add(2 * time.Second).UTC().Format(http.TimeFormat)
+		}, 500 * time.Millisecond},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var first atomic.Bool
+			var retried atomic.Int64 // Time from the start to the retry
+			start := time.Now()
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if first.CompareAndSwap(false, true) {
+					w.Header().Set("Retry-After", tt.retryAfter())
+					w.WriteHeader(tt.status)
+					return
+				}
+				retried.Store(int64(time.Since(start)))
+			}))
+			defer server.Close()
+
+			resp, err := newTestFetcher(t, server.URL).get(context.Background(), server.URL+"/page")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			resp.Body.Close()
+
+			// The backoff alone would retry within milliseconds
+			if wait := time.Duration(retried.Load()); wait < tt.minWait {
+				t.Errorf("retried after %s, want at least %s", wait, tt.minWait)
+			}
+		})
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	tests := []struct {
+		value  string
+		want   time.Duration
+		wantOK bool
+	}{
+		{"", 0, false},
+		{"0", 0, true},
+		{"120", 2 * time.Minute, true},
+		{" 5 ", 5 * time.Second, true},
+		{"-1", 0, false},
+		{"soon", 0, false},
+		{"Wed, 21 Oct 2015 07:28:00 GMT", 0, true}, // In the past
+	}
+
+	for _, tt := range tests {
+		got, ok := parseRetryAfter(tt.value)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("parseRetryAfter(%q) = %s, %v, want %s, %v", tt.value, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestDownloadReadTimeout(t *testing.T) {
+	const size = 10
+
+	tests := []struct {
+		name    string
+		gap     time.Duration // Pause before each byte after the first
+		stallAt int           // Bytes sent before the server stops sending, or 0 to send all
+		wantErr bool
+	}{
+		{"slow but steady", 20 * time.Millisecond, 0, false},
+		{"stalled", 0, 4, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Length", fmt.Sprint(size))
+				for i := 0; i < size; i++ {
+					if i == tt.stallAt && tt.stallAt > 0 {
+						<-r.Context().Done()
+						return
+					}
+					if i > 0 {
+						time.Sleep(tt.gap)
+					}
+					w.Write([]byte("x"))
+					w.(http.Flusher).Flush()
+				}
+			}))
+			defer server.Close()
+
+			f := newTestFetcher(t, server.URL)
+			f.readTimeout = 100 * time.Millisecond
+			dest := filepath.Join(t.TempDir(), "game.zip")
+
+			start := time.Now()
+			result, err := f.downloadTo(context.Background(), f.fileClient, server.URL+"/file", dest)
+			if !tt.wantErr {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if result.Size != size {
+					t.Errorf("size = %d, want %d", result.Size, size)
+				}
+				return
+			}
+
+			if !errors.Is(err, ErrTransient) || !errors.Is(err, errReadTimeout) {
+				t.Fatalf("error = %v, want a transient read timeout", err)
+			}
+			if elapsed := time.Since(start); elapsed > 5*time.Second {
+				t.Errorf("stalled download took %s to fail", elapsed)
+			}
+			if _, err := os.Stat(dest); !os.IsNotExist(err) {
+				t.Errorf("stalled download left %s behind: %v", dest, err)
+			}
+		})
+	}
+}
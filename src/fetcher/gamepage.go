@@ -0,0 +1,117 @@
+package fetcher
+
+import (
+	"context"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// FetchGamePage fetches a game's own page and parses its "More information" table,
+// upload list and CSRF token
+func (f *JamFetcher) FetchGamePage(ctx context.Context, gameURL string) (*GamePage, error) {
+	gameURL = strings.TrimRight(f.ResolveURL(gameURL), "/")
+
+	doc, err := f.fetchHTMLDoc(ctx, gameURL)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &GamePage{
+		URL:          gameURL,
+		Info:         make(map[string]string),
+		FieldSources: make(map[string]string),
+		Uploads:      parseUploads(doc, gameURL),
+		CSRFToken:    doc.Find(`meta[name="csrf_token"]`).AttrOr("value", ""),
+	}
+
+	doc.Find(".game_info_panel_widget table tr").Each(func(i int, row *goquery.Selection) {
+		cells := row.Find("td")
+		if cells.Length() < 2 {
+			return
+		}
+
+		label := strings.TrimSpace(cells.Eq(0).Text())
+		value := cells.Eq(1)
+		text := cellText(value)
+		if label == "" {
+			return
+		}
+		page.Info[label] = text
+		page.FieldSources[strings.ReplaceAll(strings.ToLower(label), " ", "_")] = SourceSelector
+
+		switch strings.ToLower(label) {
+		case "status":
+			page.Status = text
+		case "genre":
+			page.Genre = text
+		case "made with":
+			page.MadeWith = listValues(value)
+		case "tags":
+			page.Tags = listValues(value)
+		case "average session":
+			page.AverageSession = text
+		case "languages":
+			page.Languages = listValues(value)
+		case "inputs":
+			page.Inputs = listValues(value)
+		case "accessibility":
+			page.Accessibility = listValues(value)
+		case "license":
+			page.License = text
+		}
+	})
+
+	// Structured data, where the page has it, wins over the table
+	ldGame := findJSONLD(jsonLDObjects(doc), "Product", "VideoGame", "SoftwareApplication")
+	sources := fieldSources(page.FieldSources)
+	page.Genre = sources.pick("genre",
+		sourced{SourceJSONLD, stringField(ldGame, "genre")},
+		sourced{SourceSelector, page.Genre})
+	page.License = sources.pick("license",
+		sourced{SourceJSONLD, stringField(ldGame, "license")},
+		sourced{SourceSelector, page.License})
+
+	return page, nil
+}
+
+// cellText returns the text of an info panel cell. Cells that only hold a list of
+// links get the links joined with ", " like Tags, since their text runs together.
+func cellText(cell *goquery.Selection) string {
+	text := strings.Join(strings.Fields(cell.Text()), " ")
+
+	var links []string
+	rest := cell.Text()
+	cell.Find("a").Each(func(i int, a *goquery.Selection) {
+		if value := strings.TrimSpace(a.Text()); value != "" {
+			links = append(links, value)
+			rest = strings.Replace(rest, a.Text(), "", 1)
+		}
+	})
+	if len(links) == 0 || strings.Trim(rest, ", \t\r\n") != "" {
+		return text
+	}
+	return strings.Join(links, ", ")
+}
+
+// listValues returns the items of a table cell, taken from its links or, without
+// links, from its comma-separated text
+func listValues(cell *goquery.Selection) []string {
+	var values []string
+
+	cell.Find("a").Each(func(i int, a *goquery.Selection) {
+		if value := strings.TrimSpace(a.Text()); value != "" {
+			values = append(values, value)
+		}
+	})
+	if len(values) > 0 {
+		return values
+	}
+
+	for _, value := range strings.Split(cell.Text(), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
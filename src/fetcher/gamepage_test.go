@@ -0,0 +1,157 @@
+package fetcher
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// gamePages are game pages with a full info table, one whose genre is also in its
+// structured data, and one without the table
+var gamePages = map[string]string{
+	"/games/alpha": `<head><meta name="csrf_token" value="token123"></head><body>
+<div class="game_info_panel_widget"><table>
+<tr><td>Status</td><td>Released</td></tr>
+<tr><td>Genre</td><td><a href="/games/genre-puzzle">Puzzle</a></td></tr>
+<tr><td>Tags</td><td><a href="/games/tag-cube">cube</a><a href="/games/tag-short">short</a></td></tr>
+<tr><td>Made with</td><td><a href="/games/made-with-godot">Godot</a></td></tr>
+<tr><td>Average session</td><td>A few
+   minutes</td></tr>
+<tr><td>Inputs</td><td><a href="/games/input-keyboard">Keyboard</a>, <a href="/games/input-mouse">Mouse</a></td></tr>
+<tr><td>Languages</td><td>English, French</td></tr>
+<tr><td>Published</td><td>Mar 01, 2025 by <a href="https://ann.itch.io">ann</a></td></tr>
+<tr><td>License</td><td>MIT</td></tr>
+<tr><td>Lonely cell</td></tr>
+<tr><td> </td><td>No label</td></tr>
+</table></div></body>`,
+	"/games/beta": `<head><script type="application/ld+json">{"@type":"VideoGame","genre":"Platformer"}</script></head><body>
+<div class="game_info_panel_widget"><table>
+<tr><td>Genre</td><td><a href="/games/genre-action">Action</a></td></tr>
+</table></div></body>`,
+	"/games/gamma": `<body><div class="formatted_description">No info panel here</div></body>`,
+}
+
+func TestFetchGamePage(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page, ok := gamePages[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprintf(w, "<html>%s</html>", page)
+	}))
+	defer server.Close()
+	f := newTestFetcher(t, server.URL)
+
+	tests := []struct {
+		name    string
+		gameURL string
+		want    GamePage
+	}{
+		{
+			name:    "linked and plain cells",
+			gameURL: "/games/alpha/",
+			want: GamePage{
+				Status:         "Released",
+				Genre:          "Puzzle",
+				Tags:           []string{"cube", "short"},
+				MadeWith:       []string{"Godot"},
+				AverageSession: "A few minutes",
+				Inputs:         []string{"Keyboard", "Mouse"},
+				Languages:      []string{"English", "French"},
+				License:        "MIT",
+				Info: map[string]string{
+					"Status":          "Released",
+					"Genre":           "Puzzle",
+					"Tags":            "cube, short",
+					"Made with":       "Godot",
+					"Average session": "A few minutes",
+					"Inputs":          "Keyboard, Mouse",
+					"Languages":       "English, French",
+					"Published":       "Mar 01, 2025 by ann",
+					"License":         "MIT",
+				},
+				FieldSources: map[string]string{
+					"status": SourceSelector, "genre": SourceSelector, "tags": SourceSelector, "made_with": SourceSelector,
+					"average_session": SourceSelector, "inputs": SourceSelector, "languages": SourceSelector,
+					"published": SourceSelector, "license": SourceSelector,
+				},
+				CSRFToken: "token123",
+			},
+		},
+		{
+			name:    "structured data wins over the table",
+			gameURL: "/games/beta",
+			want: GamePage{
+				Genre:        "Platformer",
+				Info:         map[string]string{"Genre": "Action"},
+				FieldSources: map[string]string{"genre": SourceJSONLD},
+			},
+		},
+		{
+			name:    "missing table",
+			gameURL: "/games/gamma",
+			want: GamePage{
+				Info:         map[string]string{},
+				FieldSources: map[string]string{},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, err := f.FetchGamePage(context.Background(), tt.gameURL)
+			if err != nil {
+				t.Fatalf("FetchGamePage: %v", err)
+			}
+
+			want := tt.want
+			want.URL = server.URL + strings.TrimRight(tt.gameURL, "/")
+			page.Uploads = nil
+			if !reflect.DeepEqual(*page, want) {
+				t.Errorf("page =\n%+v\nwant\n%+v", *page, want)
+			}
+		})
+	}
+}
+
+func TestCellValues(t *testing.T) {
+	tests := []struct {
+		name     string
+		cell     string
+		wantText string
+		wantList []string
+	}{
+		{"plain text", `Released`, "Released", []string{"Released"}},
+		{"comma-separated text", `English,  French ,`, "English, French ,", []string{"English", "French"}},
+		{"one link", `<a>Puzzle</a>`, "Puzzle", []string{"Puzzle"}},
+		{"links run together", `<a>cube</a><a>short</a>`, "cube, short", []string{"cube", "short"}},
+		{"links separated by commas", `<a>Keyboard</a>, <a>Mouse</a>`, "Keyboard, Mouse", []string{"Keyboard", "Mouse"}},
+		{"link inside other text", `Mar 01, 2025 by <a>ann</a>`, "Mar 01, 2025 by ann", []string{"ann"}},
+		{"empty links are ignored", `<a> </a>Plain`, "Plain", []string{"Plain"}},
+		{"empty cell", ``, "", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := goquery.NewDocumentFromReader(strings.NewReader("<table><tr><td>" + tt.cell + "</td></tr></table>"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			cell := doc.Find("td")
+
+			if got := cellText(cell); got != tt.wantText {
+				t.Errorf("cellText = %q, want %q", got, tt.wantText)
+			}
+			if got := listValues(cell); !reflect.DeepEqual(got, tt.wantList) {
+				t.Errorf("listValues = %q, want %q", got, tt.wantList)
+			}
+		})
+	}
+}
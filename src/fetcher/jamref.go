@@ -0,0 +1,158 @@
+package fetcher
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// JamRef identifies a jam by both its URL slug and its numeric ID. The slug is the
+// canonical identity: jam pages are addressed by it and output directories are named
+// after it. The numeric ID is what the entries endpoint expects.
+type JamRef struct {
+	Slug string `json:"slug"`
+	ID   string `json:"id"`
+
+	// Responses ResolveJam already fetched to fill in the slug or ID, which
+	// FetchJamMetadata and JamEntries reuse instead of fetching them again
+	page    *goquery.Document
+	entries *JamEntriesResponse
+}
+
+// Key returns the canonical identity of the jam, used to name its output directory
+func (r JamRef) Key() string {
+	return r.Slug
+}
+
+// String returns the jam's slug and numeric ID for log messages
+func (r JamRef) String() string {
+	switch {
+	case r.Slug == "":
+		return "#" + r.ID
+	case r.ID == "":
+		return r.Slug
+	}
+	return fmt.Sprintf("%s (#%s)", r.Slug, r.ID)
+}
+
+var (
+	numericRe    = regexp.MustCompile(`^\d+$`)
+	slugRe       = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+	jamIDQueryRe = regexp.MustCompile(`[?&]jam_id=(\d+)`)
+	jamPathRe    = regexp.MustCompile(`/jam/([^/?#]+)/rate/`)
+)
+
+// ResolveJam turns a jam reference into a JamRef with both the slug and the numeric
+// ID filled in. It accepts slugs, numeric IDs, and jam, rate, entries and results URLs.
+func (f *JamFetcher) ResolveJam(ctx context.Context, input string) (JamRef, error) {
+	input = strings.TrimSpace(input)
+
+	var ref JamRef
+	switch {
+	case input == "":
+		return ref, fmt.Errorf("empty jam reference")
+	case numericRe.MatchString(input):
+		ref.ID = input
+	case slugRe.MatchString(input):
+		ref.Slug = input
+	default:
+		jamURL := f.ResolveURL(input)
+
+		// Rate, entries and results URLs all start with the jam's own path, which
+		// may hold either the slug or the numeric ID
+		if matches := f.jamURLRe.FindStringSubmatch(jamURL); len(matches) >= 2 {
+			if numericRe.MatchString(matches[1]) {
+				ref.ID = matches[1]
+			} else {
+				ref.Slug = matches[1]
+			}
+		}
+		if matches := jamIDQueryRe.FindStringSubmatch(jamURL); len(matches) >= 2 {
+			ref.ID = matches[1]
+		}
+
+		if ref.Slug == "" && ref.ID == "" {
+			pageRef, err := f.jamRefFromPage(ctx, jamURL)
+			if err != nil {
+				return ref, err
+			}
+			ref = pageRef
+		}
+	}
+
+	if ref.ID == "" {
+		doc, err := f.fetchHTMLDoc(ctx, fmt.Sprintf("%s/jam/%s", f.baseURL, ref.Slug))
+		if err != nil {
+			return ref, err
+		}
+		id, err := extractInternalIDFromPage(doc, findInitObject(initObjects(doc), "jam"), fieldSources{})
+		if err != nil {
+			return ref, fmt.Errorf("could not resolve numeric ID of jam %s: %w", ref.Slug, err)
+		}
+		ref.ID = id
+		ref.page = doc
+	}
+
+	if ref.Slug == "" {
+		entries, err := f.FetchJamEntries(ctx, ref.ID)
+		if err != nil {
+			return ref, fmt.Errorf("could not resolve slug of jam %s: %w", ref.ID, err)
+		}
+		slug, err := slugFromEntries(ref.ID, entries)
+		if err != nil {
+			return ref, err
+		}
+		ref.Slug = slug
+		ref.entries = entries
+	}
+
+	return ref, nil
+}
+
+// jamRefFromPage reads whatever the page at pageURL says about its jam, for URLs
+// that do not carry the jam in their path
+func (f *JamFetcher) jamRefFromPage(ctx context.Context, pageURL string) (JamRef, error) {
+	var ref JamRef
+
+	doc, err := f.fetchHTMLDoc(ctx, pageURL)
+	if err != nil {
+		return ref, err
+	}
+
+	for _, link := range []string{
+		doc.Find(`link[rel="canonical"]`).AttrOr("href", ""),
+		doc.Find(`meta[property="og:url"]`).AttrOr("content", ""),
+	} {
+		if matches := f.jamURLRe.FindStringSubmatch(link); len(matches) >= 2 && !numericRe.MatchString(matches[1]) {
+			ref.Slug = matches[1]
+			break
+		}
+	}
+
+	// Look for randomizer link which contains the jam ID
+	randomizerLink := doc.Find("a.randomizer_link").AttrOr("href", "")
+	if matches := jamIDQueryRe.FindStringSubmatch(randomizerLink); len(matches) >= 2 {
+		ref.ID = matches[1]
+	} else if id := stringField(findInitObject(initObjects(doc), "jam"), "id"); id != "" {
+		ref.ID = id
+	}
+
+	if ref.Slug == "" && ref.ID == "" {
+		return ref, newParseError(pageURL, "could not find a jam on the page")
+	}
+	return ref, nil
+}
+
+// slugFromEntries looks up a jam's slug from the rate page URLs of its entries, since
+// jam pages cannot be addressed by numeric ID
+func slugFromEntries(jamID string, entries *JamEntriesResponse) (string, error) {
+	for _, entry := range entries.JamGames {
+		if matches := jamPathRe.FindStringSubmatch(entry.URL); len(matches) >= 2 {
+			return matches[1], nil
+		}
+	}
+	return "", fmt.Errorf("could not resolve slug of jam %s: it has no entries", jamID)
+}
@@ -0,0 +1,138 @@
+package fetcher
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+// newJamServer serves the page and entries of a jam with slug test-jam and ID 4242,
+// counting the requests for each path
+func newJamServer(t *testing.T) (*httptest.Server, func(path string) int) {
+	t.Helper()
+	var mu sync.Mutex
+	hits := make(map[string]int)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/jam/test-jam", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<html><head><script>I.ViewJam("#jam", {"jam":{"id":4242,"title":"Test Jam"}})</script></head><body><h1 class="jam_title_header">Test Jam</h1></body></html>`)
+	})
+	mux.HandleFunc("/jam/4242/entries.json", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"jam_games":[{"id":11,"url":"/jam/test-jam/rate/101","game":{"id":101,"title":"Alpha"}}]}`)
+	})
+	mux.HandleFunc("/games/alpha", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<html><head><link rel="canonical" href="http://`+r.Host+`/jam/test-jam"></head><body><a class="randomizer_link" href="/randomizer?jam_id=4242">Random</a></body></html>`)
+	})
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		hits[r.URL.Path]++
+		mu.Unlock()
+		mux.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	return server, func(path string) int {
+		mu.Lock()
+		defer mu.Unlock()
+		return hits[path]
+	}
+}
+
+func TestResolveJam(t *testing.T) {
+	server, _ := newJamServer(t)
+	f := newTestFetcher(t, server.URL)
+
+	tests := []struct {
+		name    string
+		input   string
+		want    JamRef
+		wantErr bool
+	}{
+		{"slug", "test-jam", JamRef{Slug: "test-jam", ID: "4242"}, false},
+		{"numeric ID", "4242", JamRef{Slug: "test-jam", ID: "4242"}, false},
+		{"padded slug", "  test-jam ", JamRef{Slug: "test-jam", ID: "4242"}, false},
+		{"jam URL", server.URL + "/jam/test-jam", JamRef{Slug: "test-jam", ID: "4242"}, false},
+		{"relative jam URL", "/jam/test-jam", JamRef{Slug: "test-jam", ID: "4242"}, false},
+		{"rate URL", server.URL + "/jam/test-jam/rate/101", JamRef{Slug: "test-jam", ID: "4242"}, false},
+		{"results URL", server.URL + "/jam/test-jam/results/fun", JamRef{Slug: "test-jam", ID: "4242"}, false},
+		{"entries URL", server.URL + "/jam/4242/entries.json", JamRef{Slug: "test-jam", ID: "4242"}, false},
+		{"jam_id query", server.URL + "/jam/test-jam/entries?jam_id=4242", JamRef{Slug: "test-jam", ID: "4242"}, false},
+		{"page of the jam", server.URL + "/games/alpha", JamRef{Slug: "test-jam", ID: "4242"}, false},
+		{"empty", " ", JamRef{}, true},
+		{"unknown slug", "no-such-jam", JamRef{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := f.ResolveJam(context.Background(), tt.input)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("ResolveJam(%q) = %v, want an error", tt.input, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ResolveJam(%q): %v", tt.input, err)
+			}
+			if got.Slug != tt.want.Slug || got.ID != tt.want.ID {
+				t.Errorf("ResolveJam(%q) = %v, want %v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveJamFetchesOnce(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"slug", "test-jam"},
+		{"numeric ID", "4242"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, hits := newJamServer(t)
+			f := newTestFetcher(t, server.URL)
+			ctx := context.Background()
+
+			jam, err := f.ResolveJam(ctx, tt.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := f.FetchJamMetadata(ctx, jam); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := f.JamEntries(ctx, jam); err != nil {
+				t.Fatal(err)
+			}
+
+			for _, path := range []string{"/jam/test-jam", "/jam/4242/entries.json"} {
+				if n := hits(path); n != 1 {
+					t.Errorf("%s fetched %d times, want once", path, n)
+				}
+			}
+		})
+	}
+}
+
+func TestJamRefString(t *testing.T) {
+	tests := []struct {
+		ref  JamRef
+		want string
+	}{
+		{JamRef{Slug: "test-jam", ID: "4242"}, "test-jam (#4242)"},
+		{JamRef{Slug: "test-jam"}, "test-jam"},
+		{JamRef{ID: "4242"}, "#4242"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.ref.String(); got != tt.want {
+			t.Errorf("String() = %q, want %q", got, tt.want)
+		}
+	}
+}
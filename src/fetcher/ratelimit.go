@@ -0,0 +1,115 @@
+package fetcher
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// slowdownFactor divides the request rate while the limiter is cooling down after a 429
+const slowdownFactor = 4
+
+// rateLimiter is a token bucket shared by every goroutine that makes requests
+type rateLimiter struct {
+	mu        sync.Mutex
+	rate      float64 // Tokens added per second
+	burst     float64 // Maximum number of stored tokens
+	tokens    float64
+	last      time.Time
+	slowUntil time.Time // Rate is reduced until this time
+}
+
+// newRateLimiter creates a token bucket allowing rps requests per second with the given burst
+func newRateLimiter(rps float64, burst int) *rateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+
+	return &rateLimiter{
+		rate:   rps,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// Wait blocks until a token is available or the context is cancelled. Tokens are
+// reserved up front, so concurrent callers queue up behind each other instead of
+// firing together.
+func (l *rateLimiter) Wait(ctx context.Context) error {
+	if l == nil || l.rate <= 0 {
+		return ctx.Err()
+	}
+
+	l.mu.Lock()
+	now := time.Now()
+	rate := l.currentRate(now)
+	l.tokens += now.Sub(l.last).Seconds() * rate
+	if l.tokens > l.burst {
+		l.tokens = l.burst
+	}
+	l.last = now
+
+	l.tokens--
+	var wait time.Duration
+	if l.tokens < 0 {
+		wait = time.Duration(-l.tokens / rate * float64(time.Second))
+	}
+	l.mu.Unlock()
+
+	return sleepContext(ctx, wait)
+}
+
+// sleepContext sleeps for the given duration, returning early if the context is cancelled
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// SlowDown holds back every caller for at least wait and reduces the rate for cooldown.
+// It is called when the server tells us we are sending too many requests.
+func (l *rateLimiter) SlowDown(wait, cooldown time.Duration) {
+	if l == nil || l.rate <= 0 {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	if until := now.Add(cooldown); until.After(l.slowUntil) {
+		l.slowUntil = until
+	}
+
+	// Drain the bucket so the next token only becomes available after wait
+	if owed := -wait.Seconds() * l.currentRate(now); l.tokens > owed {
+		l.tokens = owed
+	}
+}
+
+// currentRate returns the refill rate, reduced while cooling down
+func (l *rateLimiter) currentRate(now time.Time) float64 {
+	if now.Before(l.slowUntil) {
+		return l.rate / slowdownFactor
+	}
+	return l.rate
+}
+
+// estimate returns how long n requests take at the limiter's normal rate, starting with a full bucket
+func (l *rateLimiter) estimate(n int) time.Duration {
+	if l == nil || l.rate <= 0 || float64(n) <= l.burst {
+		return 0
+	}
+	return time.Duration((float64(n) - l.burst) / l.rate * float64(time.Second))
+}
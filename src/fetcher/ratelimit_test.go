@@ -0,0 +1,103 @@
+package fetcher
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRateLimiterPace(t *testing.T) {
+	tests := []struct {
+		name     string
+		rps      float64
+		burst    int
+		workers  int
+		requests int // Per worker
+		minTime  time.Duration
+	}{
+		{"burst only", 100, 5, 1, 5, 0},
+		{"one worker", 100, 1, 1, 6, 50 * time.Millisecond},
+		{"shared by workers", 100, 2, 4, 3, 100 * time.Millisecond},
+		{"burst then pace", 50, 4, 2, 4, 80 * time.Millisecond},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limiter := newRateLimiter(tt.rps, tt.burst)
+			start := time.Now()
+
+			var wg sync.WaitGroup
+			for range tt.workers {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for range tt.requests {
+						if err := limiter.Wait(context.Background()); err != nil {
+							t.Error(err)
+						}
+					}
+				}()
+			}
+			wg.Wait()
+
+			// Workers share one bucket, so adding workers does not raise the rate
+			if elapsed := time.Since(start); elapsed < tt.minTime {
+				t.Errorf("%d requests took %s, want at least %s", tt.workers*tt.requests, elapsed, tt.minTime)
+			}
+		})
+	}
+}
+
+func TestRateLimiterSlowDown(t *testing.T) {
+	limiter := newRateLimiter(1000, 10)
+	limiter.SlowDown(100*time.Millisecond, time.Minute)
+
+	start := time.Now()
+	if err := limiter.Wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+		t.Errorf("first request after SlowDown waited %s, want at least 100ms", elapsed)
+	}
+	if rate := limiter.currentRate(time.Now()); rate != 1000/slowdownFactor {
+		t.Errorf("rate while cooling down = %v, want %v", rate, 1000/slowdownFactor)
+	}
+	if rate := limiter.currentRate(time.Now().Add(2 * time.Minute)); rate != 1000 {
+		t.Errorf("rate after cooling down = %v, want 1000", rate)
+	}
+}
+
+func TestRateLimiterCancel(t *testing.T) {
+	limiter := newRateLimiter(0.1, 1)
+	if err := limiter.Wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := limiter.Wait(ctx); err != context.DeadlineExceeded {
+		t.Errorf("Wait on an empty bucket = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestRateLimiterEstimate(t *testing.T) {
+	tests := []struct {
+		rps   float64
+		burst int
+		n     int
+		want  time.Duration
+	}{
+		{2, 1, 1, 0},
+		{2, 1, 5, 2 * time.Second},
+		{2, 4, 4, 0},
+		{0.5, 1, 3, 4 * time.Second},
+		{0, 1, 100, 0}, // Unlimited
+	}
+
+	for _, tt := range tests {
+		if got := newRateLimiter(tt.rps, tt.burst).estimate(tt.n); got != tt.want {
+			t.Errorf("estimate(%d) at %v rps, burst %d = %s, want %s", tt.n, tt.rps, tt.burst, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,249 @@
+package fetcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// maxResultPages guards against following a broken pager forever
+const maxResultPages = 1000
+
+var (
+	rankRe        = regexp.MustCompile(`#(\d+)`)
+	ratingCountRe = regexp.MustCompile(`(\d+)\s+ratings?`)
+	rateGameIDRe  = regexp.MustCompile(`/rate/(\d+)`)
+)
+
+// FetchJamResults fetches the final rankings of a finished jam from its results page
+// and per-criteria result pages. If a per-criteria page fails, the results gathered
+// from the other pages are returned along with the error.
+func (f *JamFetcher) FetchJamResults(ctx context.Context, jamID string) (*JamResults, error) {
+	resultsURL := fmt.Sprintf("%s/jam/%s/results", f.baseURL, jamID)
+
+	results := &JamResults{JamID: jamID}
+	entries := make(map[int]*EntryResult)
+	var order []int
+	criteriaURLs := make(map[string]string)
+	var criteriaOrder []string
+
+	// The overall rank is only read from the overall pages; a criteria page ranks
+	// entries by that criterion alone
+	collect := func(doc *goquery.Document, overall bool) {
+		doc.Find(".game_rank").Each(func(i int, s *goquery.Selection) {
+			entry := f.parseEntryResult(s, overall)
+			if entry.GameID == 0 {
+				return
+			}
+			if existing, ok := entries[entry.GameID]; ok {
+				mergeEntryResult(existing, entry)
+				return
+			}
+			entries[entry.GameID] = entry
+			order = append(order, entry.GameID)
+		})
+	}
+
+	// Overall results, which also list every criterion for each entry
+	criteriaLinkRe := regexp.MustCompile(`/jam/` + regexp.QuoteMeta(jamID) + `/results/([^/?#]+)$`)
+	err := f.walkResultPages(ctx, resultsURL, func(doc *goquery.Document) {
+		collect(doc, true)
+
+		doc.Find("a[href]").Each(func(i int, s *goquery.Selection) {
+			href := f.ResolveURL(s.AttrOr("href", ""))
+			if !criteriaLinkRe.MatchString(href) {
+				return
+			}
+			if _, ok := criteriaURLs[href]; !ok {
+				criteriaURLs[href] = strings.TrimSpace(s.Text())
+				criteriaOrder = append(criteriaOrder, href)
+			}
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Per-criteria pages fill in entries that are ranked for a criterion but
+	// missing from the overall listing
+	var criteriaErrs []error
+	for _, criteriaURL := range criteriaOrder {
+		err := f.walkResultPages(ctx, criteriaURL, func(doc *goquery.Document) {
+			collect(doc, false)
+		})
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			criteriaErrs = append(criteriaErrs, fmt.Errorf("%s results: %w", criteriaURLs[criteriaURL], err))
+			// Still rate limited after every retry: the other pages would be too
+			if errors.Is(err, ErrRateLimited) {
+				break
+			}
+		}
+	}
+
+	seenCriteria := make(map[string]bool)
+	for _, id := range order {
+		entry := entries[id]
+		results.Entries = append(results.Entries, *entry)
+		for _, criterion := range entry.Criteria {
+			if !seenCriteria[criterion.Name] {
+				seenCriteria[criterion.Name] = true
+				results.Criteria = append(results.Criteria, criterion.Name)
+			}
+		}
+	}
+
+	return results, errors.Join(criteriaErrs...)
+}
+
+// walkResultPages visits a results page and every following page of its pager
+func (f *JamFetcher) walkResultPages(ctx context.Context, pageURL string, visit func(doc *goquery.Document)) error {
+	visited := make(map[string]bool)
+
+	for page := 0; pageURL != "" && !visited[pageURL] && page < maxResultPages; page++ {
+		visited[pageURL] = true
+
+		doc, err := f.fetchHTMLDoc(ctx, pageURL)
+		if err != nil {
+			return err
+		}
+		visit(doc)
+
+		next := doc.Find("a.next_page").AttrOr("href", "")
+		if next == "" {
+			break
+		}
+		pageURL = resolveReference(pageURL, next)
+	}
+
+	return nil
+}
+
+// parseEntryResult parses one ranked entry from a results page. The overall rank is
+// only set from overall pages, where "Ranked #N" is the entry's overall placement.
+func (f *JamFetcher) parseEntryResult(s *goquery.Selection, overall bool) *EntryResult {
+	link := s.Find(".game_summary h2 a").First()
+	entry := &EntryResult{
+		RateURL: f.ResolveURL(link.AttrOr("href", "")),
+		Title:   strings.TrimSpace(link.Text()),
+	}
+
+	if matches := rateGameIDRe.FindStringSubmatch(entry.RateURL); len(matches) >= 2 {
+		entry.GameID, _ = strconv.Atoi(matches[1])
+	}
+
+	// Summary line such as "Ranked #1 with 120 ratings (Score: 4.321)"
+	s.Find(".game_summary h3").Each(func(i int, h *goquery.Selection) {
+		text := h.Text()
+		if !strings.Contains(text, "Ranked") {
+			return
+		}
+		if matches := rankRe.FindStringSubmatch(text); overall && len(matches) >= 2 {
+			entry.Rank, _ = strconv.Atoi(matches[1])
+		}
+		if matches := ratingCountRe.FindStringSubmatch(text); len(matches) >= 2 {
+			entry.RatingCount, _ = strconv.Atoi(matches[1])
+		}
+	})
+
+	// Locate columns by their headers so a reordered table still parses
+	rankCol, scoreCol, rawCol := 1, 2, 3
+	table := s.Find(".ranking_results_table table")
+	table.Find("tr").First().Find("th").Each(func(i int, th *goquery.Selection) {
+		header := strings.ToLower(strings.TrimSpace(th.Text()))
+		switch {
+		case strings.HasPrefix(header, "raw"):
+			rawCol = i
+		case strings.HasPrefix(header, "score"):
+			scoreCol = i
+		case strings.HasPrefix(header, "rank"):
+			rankCol = i
+		}
+	})
+
+	table.Find("tr").Each(func(i int, tr *goquery.Selection) {
+		cells := tr.Find("td")
+		if cells.Length() == 0 {
+			return
+		}
+		cell := func(col int) string {
+			return strings.TrimSpace(cells.Eq(col).Text())
+		}
+
+		criterion := CriterionResult{
+			Name:     cell(0),
+			Rank:     parseInt(cell(rankCol)),
+			Score:    parseFloat(cell(scoreCol)),
+			RawScore: parseFloat(cell(rawCol)),
+		}
+
+		if strings.EqualFold(criterion.Name, "Overall") {
+			if overall && entry.Rank == 0 {
+				entry.Rank = criterion.Rank
+			}
+			entry.Score = criterion.Score
+			entry.RawScore = criterion.RawScore
+			return
+		}
+		entry.Criteria = append(entry.Criteria, criterion)
+	})
+
+	return entry
+}
+
+// mergeEntryResult copies criteria and overall values missing from existing out of parsed
+func mergeEntryResult(existing, parsed *EntryResult) {
+	if existing.Rank == 0 {
+		existing.Rank = parsed.Rank
+	}
+	if existing.Score == 0 && existing.RawScore == 0 {
+		existing.Score = parsed.Score
+		existing.RawScore = parsed.RawScore
+	}
+	if existing.RatingCount == 0 {
+		existing.RatingCount = parsed.RatingCount
+	}
+
+	for _, criterion := range parsed.Criteria {
+		found := false
+		for _, known := range existing.Criteria {
+			if known.Name == criterion.Name {
+				found = true
+				break
+			}
+		}
+		if !found {
+			existing.Criteria = append(existing.Criteria, criterion)
+		}
+	}
+}
+
+// resolveReference resolves a possibly relative link against the page it was found on
+func resolveReference(pageURL, href string) string {
+	base, err := url.Parse(pageURL)
+	if err != nil {
+		return href
+	}
+	ref, err := url.Parse(href)
+	if err != nil {
+		return href
+	}
+	return base.ResolveReference(ref).String()
+}
+
+// parseFloat parses a score such as "4.321", returning 0 if parsing fails
+func parseFloat(s string) float64 {
+	value, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil {
+		return 0
+	}
+	return value
+}
@@ -0,0 +1,141 @@
+package fetcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// resultRow renders one ranked entry the way itch.io's results pages do
+func resultRow(gameID int, title string, rank int, score float64, criterion string, criterionRank int) string {
+	return fmt.Sprintf(`<div class="game_rank">
+<div class="game_summary"><h2><a href="/jam/test-jam/rate/%d">%s</a></h2><h3>Ranked <strong>#%d</strong> with 12 ratings (Score: %.3f)</h3></div>
+<div class="ranking_results_table"><table>
+<tr><th>Criteria</th><th>Rank</th><th>Score*</th><th>Raw Score</th></tr>
+<tr><td>Overall</td><td>#%d</td><td>%.3f</td><td>%.3f</td></tr>
+<tr><td>%s</td><td>#%d</td><td>3.500</td><td>3.600</td></tr>
+</table></div></div>`, gameID, title, rank, score, rank, score, score+0.1, criterion, criterionRank)
+}
+
+// newResultsServer serves a jam's results over two pages and a Fun criteria page,
+// which ranks an entry missing from the overall listing. A criteria status other
+// than 200 makes the criteria page fail.
+func newResultsServer(t *testing.T, criteriaStatus int) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/jam/test-jam/results", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("page") == "2" {
+			fmt.Fprint(w, `<html><body>`+resultRow(101, "Alpha", 2, 4.0, "Fun", 1)+`</body></html>`)
+			return
+		}
+		fmt.Fprint(w, `<html><body><a href="/jam/test-jam/results/fun">Fun</a>`+
+			resultRow(102, "Beta", 1, 4.5, "Fun", 2)+
+			`<a class="next_page" href="?page=2">Next</a></body></html>`)
+	})
+	mux.HandleFunc("/jam/test-jam/results/fun", func(w http.ResponseWriter, r *http.Request) {
+		if criteriaStatus != http.StatusOK {
+			w.WriteHeader(criteriaStatus)
+			return
+		}
+		fmt.Fprint(w, `<html><body>`+resultRow(101, "Alpha", 2, 4.0, "Fun", 1)+resultRow(103, "Gamma", 3, 3.0, "Fun", 3)+`</body></html>`)
+	})
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestFetchJamResults(t *testing.T) {
+	server := newResultsServer(t, http.StatusOK)
+
+	results, err := newTestFetcher(t, server.URL).FetchJamResults(context.Background(), "test-jam")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []struct {
+		gameID   int
+		title    string
+		rank     int
+		score    float64
+		funRank  int
+		ratings  int
+		rawScore float64
+	}{
+		{102, "Beta", 1, 4.5, 2, 12, 4.6},
+		{101, "Alpha", 2, 4.0, 1, 12, 4.1},
+		{103, "Gamma", 0, 3.0, 3, 12, 3.1}, // Only on the Fun page, so it has no overall rank
+	}
+	if len(results.Entries) != len(want) {
+		t.Fatalf("got %d entries, want %d", len(results.Entries), len(want))
+	}
+	for i, w := range want {
+		entry := results.Entries[i]
+		if entry.GameID != w.gameID || entry.Title != w.title || entry.Rank != w.rank || entry.Score != w.score ||
+			entry.RatingCount != w.ratings || entry.RawScore != w.rawScore {
+			t.Errorf("entry %d = %+v, want %+v", i, entry, w)
+		}
+		if want := server.URL + fmt.Sprintf("/jam/test-jam/rate/%d", w.gameID); entry.RateURL != want {
+			t.Errorf("entry %d rate URL = %s, want %s", i, entry.RateURL, want)
+		}
+		if len(entry.Criteria) != 1 || entry.Criteria[0].Name != "Fun" || entry.Criteria[0].Rank != w.funRank {
+			t.Errorf("entry %d criteria = %+v, want Fun ranked #%d", i, entry.Criteria, w.funRank)
+		}
+	}
+	if len(results.Criteria) != 1 || results.Criteria[0] != "Fun" {
+		t.Errorf("criteria = %v, want [Fun]", results.Criteria)
+	}
+}
+
+func TestFetchJamResultsCriteriaPageFails(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		wantErr error
+	}{
+		{"missing", http.StatusNotFound, ErrNotFound},
+		{"rate limited", http.StatusTooManyRequests, ErrRateLimited},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newResultsServer(t, tt.status)
+
+			results, err := newTestFetcher(t, server.URL).FetchJamResults(context.Background(), "test-jam")
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("error = %v, want %v", err, tt.wantErr)
+			}
+			// The overall ranking is still returned
+			if results == nil || len(results.Entries) != 2 {
+				t.Fatalf("results = %+v, want the 2 entries of the overall pages", results)
+			}
+		})
+	}
+}
+
+func TestParseResultNumbers(t *testing.T) {
+	tests := []struct {
+		text      string
+		wantInt   int
+		wantFloat float64
+	}{
+		{"#1", 1, 0},
+		{"#12", 12, 0},
+		{"4.321", 4321, 4.321},
+		{" 3.5 ", 35, 3.5},
+		{"", 0, 0},
+		{"n/a", 0, 0},
+	}
+
+	for _, tt := range tests {
+		if got := parseInt(tt.text); got != tt.wantInt {
+			t.Errorf("parseInt(%q) = %d, want %d", tt.text, got, tt.wantInt)
+		}
+		if got := parseFloat(tt.text); got != tt.wantFloat {
+			t.Errorf("parseFloat(%q) = %v, want %v", tt.text, got, tt.wantFloat)
+		}
+	}
+}
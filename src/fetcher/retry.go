@@ -0,0 +1,95 @@
+package fetcher
+
+import (
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Retry defaults used when the configuration leaves them unset
+const (
+	defaultMaxAttempts    = 4
+	defaultRetryBaseDelay = time.Second
+	defaultRetryMaxDelay  = time.Minute
+
+	// How long the shared pace stays reduced after itch.io answers 429
+	rateLimitCooldown = 2 * time.Minute
+)
+
+// retryPolicy decides how often and how long to wait before repeating a failed request
+type retryPolicy struct {
+	maxAttempts int
+	baseDelay   time.Duration
+	maxDelay    time.Duration
+}
+
+// newRetryPolicy creates a retry policy, falling back to defaults for unset values
+func newRetryPolicy(maxAttempts, baseDelayMS, maxDelayMS int) retryPolicy {
+	policy := retryPolicy{
+		maxAttempts: maxAttempts,
+		baseDelay:   time.Duration(baseDelayMS) * time.Millisecond,
+		maxDelay:    time.Duration(maxDelayMS) * time.Millisecond,
+	}
+	if policy.maxAttempts <= 0 {
+		policy.maxAttempts = defaultMaxAttempts
+	}
+	if policy.baseDelay <= 0 {
+		policy.baseDelay = defaultRetryBaseDelay
+	}
+	if policy.maxDelay <= 0 {
+		policy.maxDelay = defaultRetryMaxDelay
+	}
+	return policy
+}
+
+// backoff returns the jittered delay before the given retry (1 for the first retry)
+func (p retryPolicy) backoff(retry int) time.Duration {
+	delay := p.baseDelay << (retry - 1)
+	if delay <= 0 || delay > p.maxDelay {
+		delay = p.maxDelay
+	}
+
+	// Wait somewhere between half and the full delay so workers do not retry in lockstep
+	half := delay / 2
+	return half + rand.N(half+1)
+}
+
+// isRetryableStatus reports whether a response status is worth trying again
+func isRetryableStatus(code int) bool {
+	switch code {
+	case http.StatusTooManyRequests,
+		http.StatusInternalServerError,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// parseRetryAfter reads a Retry-After header given either in seconds or as an HTTP date
+func parseRetryAfter(value string) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	if date, err := http.ParseTime(value); err == nil {
+		wait := time.Until(date)
+		if wait < 0 {
+			wait = 0
+		}
+		return wait, true
+	}
+
+	return 0, false
+}
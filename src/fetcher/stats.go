@@ -0,0 +1,63 @@
+package fetcher
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// RequestStats counts requests, retries and downloaded bytes. The fetcher keeps one for
+// the whole run, and callers can attach another to a context to count the requests
+// made on behalf of one game.
+type RequestStats struct {
+	requests atomic.Int64
+	retries  atomic.Int64
+	bytes    atomic.Int64
+}
+
+// Requests returns the number of HTTP requests made, retries included
+func (s *RequestStats) Requests() int64 { return s.requests.Load() }
+
+// Retries returns the number of requests that were retries of a failed attempt
+func (s *RequestStats) Retries() int64 { return s.retries.Load() }
+
+// Bytes returns the number of bytes written by downloads
+func (s *RequestStats) Bytes() int64 { return s.bytes.Load() }
+
+type statsKey struct{}
+
+// WithStats returns a context whose requests are also counted in stats
+func WithStats(ctx context.Context, stats *RequestStats) context.Context {
+	return context.WithValue(ctx, statsKey{}, stats)
+}
+
+// countRequest counts an attempt in the run's stats and in the context's, if any
+func (f *JamFetcher) countRequest(ctx context.Context, attempt int) {
+	for _, stats := range []*RequestStats{&f.stats, statsFrom(ctx)} {
+		if stats == nil {
+			continue
+		}
+		stats.requests.Add(1)
+		if attempt > 1 {
+			stats.retries.Add(1)
+		}
+	}
+}
+
+// countBytes counts downloaded bytes in the run's stats and in the context's, if any
+func (f *JamFetcher) countBytes(ctx context.Context, n int64) {
+	f.stats.bytes.Add(n)
+	if stats := statsFrom(ctx); stats != nil {
+		stats.bytes.Add(n)
+	}
+}
+
+// statsFrom returns the stats attached to a context, or nil
+func statsFrom(ctx context.Context) *RequestStats {
+	stats, _ := ctx.Value(statsKey{}).(*RequestStats)
+	return stats
+}
+
+// Stats returns the requests, retries and downloaded bytes of the whole run
+func (f *JamFetcher) Stats() *RequestStats {
+	return &f.stats
+}
@@ -0,0 +1,191 @@
+package fetcher
+
+import (
+	"encoding/json"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// Sources a scraped field can come from, most reliable first
+const (
+	SourceJSONLD   = "json-ld"   // application/ld+json block
+	SourceInitJSON = "init-json" // JSON passed to the page's JavaScript initialisation
+	SourceSelector = "selector"  // CSS selector on the rendered HTML
+	SourceScript   = "script"    // Pattern match on raw script text
+)
+
+// initCallRe matches the start of itch.io's page initialisation calls, such as
+// `new I.ViewJam(` or `init_ViewGame(`, whose arguments carry the page's data as JSON
+var initCallRe = regexp.MustCompile(`(?:\bI\.\w+|\binit_\w+)\(`)
+
+// sourced is a candidate value for a field together with where it was found
+type sourced struct {
+	source string
+	value  string
+}
+
+// fieldSources records which source each field of a scraped object came from
+type fieldSources map[string]string
+
+// pick returns the first non-empty candidate and records its source for field
+func (fs fieldSources) pick(field string, candidates ...sourced) string {
+	for _, candidate := range candidates {
+		if value := strings.TrimSpace(candidate.value); value != "" {
+			fs[field] = candidate.source
+			return value
+		}
+	}
+	return ""
+}
+
+// jsonLDObjects returns every object in the page's application/ld+json blocks,
+// flattening top-level arrays and @graph lists
+func jsonLDObjects(doc *goquery.Document) []map[string]any {
+	var objects []map[string]any
+
+	var collect func(value any)
+	collect = func(value any) {
+		switch v := value.(type) {
+		case []any:
+			for _, item := range v {
+				collect(item)
+			}
+		case map[string]any:
+			objects = append(objects, v)
+			if graph, ok := v["@graph"]; ok {
+				collect(graph)
+			}
+		}
+	}
+
+	doc.Find(`script[type="application/ld+json"]`).Each(func(i int, s *goquery.Selection) {
+		var value any
+		if err := json.Unmarshal([]byte(s.Text()), &value); err == nil {
+			collect(value)
+		}
+	})
+
+	return objects
+}
+
+// findJSONLD returns the first JSON-LD object of one of the given @type values
+func findJSONLD(objects []map[string]any, types ...string) map[string]any {
+	for _, object := range objects {
+		for _, t := range types {
+			switch objectType := object["@type"].(type) {
+			case string:
+				if strings.EqualFold(objectType, t) {
+					return object
+				}
+			case []any:
+				for _, item := range objectType {
+					if s, ok := item.(string); ok && strings.EqualFold(s, t) {
+						return object
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// initObjects returns the JSON objects passed to the page's initialisation calls
+func initObjects(doc *goquery.Document) []map[string]any {
+	var objects []map[string]any
+
+	doc.Find("script").Each(func(i int, s *goquery.Selection) {
+		script := s.Text()
+		for _, loc := range initCallRe.FindAllStringIndex(script, -1) {
+			args := script[loc[1]:]
+
+			// The data object follows the selector argument, if there is one
+			end := strings.IndexByte(args, ')')
+			start := strings.IndexByte(args, '{')
+			if start < 0 || (end >= 0 && end < start) {
+				continue
+			}
+
+			var object map[string]any
+			if err := json.NewDecoder(strings.NewReader(args[start:])).Decode(&object); err == nil {
+				objects = append(objects, object)
+			}
+		}
+	})
+
+	return objects
+}
+
+// findInitObject returns the first nested object stored under key in the page's
+// initialisation data, such as the "jam" object of a jam page
+func findInitObject(objects []map[string]any, key string) map[string]any {
+	for _, object := range objects {
+		if nested, ok := object[key].(map[string]any); ok {
+			return nested
+		}
+	}
+	return nil
+}
+
+// stringField returns a JSON field as a string, accepting numbers, URLs given as
+// {"url": ...} objects and lists whose first item is usable
+func stringField(object map[string]any, key string) string {
+	if object == nil {
+		return ""
+	}
+	return stringValue(object[key])
+}
+
+// firstField returns the first of the given fields of a JSON object that is not empty
+func firstField(object map[string]any, keys ...string) string {
+	for _, key := range keys {
+		if value := strings.TrimSpace(stringField(object, key)); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// stringList returns a JSON value that may be a single item or a list as a list of
+// strings, taking the URL of image objects
+func stringList(value any) []string {
+	items, ok := value.([]any)
+	if !ok {
+		items = []any{value}
+	}
+
+	var list []string
+	for _, item := range items {
+		if image, ok := item.(map[string]any); ok && stringValue(image["contentUrl"]) != "" {
+			item = image["contentUrl"]
+		}
+		if s := strings.TrimSpace(stringValue(item)); s != "" {
+			list = append(list, s)
+		}
+	}
+	return list
+}
+
+// stringValue converts a decoded JSON value to a string, see stringField
+func stringValue(value any) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case map[string]any:
+		if s := stringValue(v["url"]); s != "" {
+			return s
+		}
+		return stringValue(v["name"])
+	case []any:
+		for _, item := range v {
+			if s := stringValue(item); s != "" {
+				return s
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,236 @@
+package fetcher
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// jamPages are jam pages that carry their data in each of the sources the metadata is
+// read from. The selector markup is on every page, so structured data has to win over it.
+var jamPages = map[string]string{
+	"ld-jam": `<head><script type="application/ld+json">
+{"@context":"https://schema.org","@graph":[
+  {"@type":"WebPage","name":"Not the jam"},
+  {"@type":["Event","CreativeWork"],"name":"LD Jam","startDate":"2025-02-20T17:00:00Z","endDate":"2025-03-01T17:00:00Z","image":{"url":"https://img.itch.zone/ld.png"}}
+]}</script>
+<script>var config = {"jam_id": 77};</script></head>` + selectorJamBody,
+	"init-jam":     `<head><script>new I.ViewJam("#jam_page", {"jam":{"id":4242,"title":"Init Jam","start_date":"2025-02-20 17:00:00","end_date":"2025-03-01 17:00:00","submission_end_date":"2025-03-01 17:00:00","theme":"Cubes","cover":"https://img.itch.zone/init.png"}});</script></head>` + selectorJamBody,
+	"selector-jam": `<head><script>var config = {"jam_id": 5};</script></head>` + selectorJamBody,
+}
+
+const selectorJamBody = `<body>
+<h1 class="jam_title_header">Selector Jam</h1>
+<div class="jam_host_header"><a href="https://host.itch.io">host</a></div>
+<div class="jam_details_widget">
+  <div class="line"><span class="label">Start date</span><span class="date_countdown">Feb 20</span></div>
+  <div class="line"><span class="label">End date</span><span class="date_countdown">Mar 1</span></div>
+</div>
+<div class="jam_theme_display">Shapes</div>
+<img class="jam_cover" src="https://img.itch.zone/selector.png">
+<div class="stat_box"><span class="stat_value">120</span><span class="stat_label">Entries</span></div>
+</body>`
+
+func TestFetchJamMetadataSources(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page, ok := jamPages[strings.TrimPrefix(r.URL.Path, "/jam/")]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprintf(w, "<html>%s</html>", page)
+	}))
+	defer server.Close()
+	f := newTestFetcher(t, server.URL)
+
+	selectorSources := map[string]string{"hosts": SourceSelector, "submission_count": SourceSelector}
+	withSources := func(sources map[string]string) map[string]string {
+		merged := map[string]string{}
+		for field, source := range selectorSources {
+			merged[field] = source
+		}
+		for field, source := range sources {
+			merged[field] = source
+		}
+		return merged
+	}
+
+	tests := []struct {
+		slug        string
+		want        JamMetadata
+		wantSources map[string]string
+	}{
+		{
+			slug: "ld-jam",
+			want: JamMetadata{
+				Title: "LD Jam", InternalID: "77", StartDate: "2025-02-20T17:00:00Z", EndDate: "2025-03-01T17:00:00Z",
+				Theme: "Shapes", CoverImageURL: "https://img.itch.zone/ld.png",
+			},
+			wantSources: withSources(map[string]string{
+				"title": SourceJSONLD, "internal_id": SourceScript, "start_date": SourceJSONLD, "end_date": SourceJSONLD,
+				"theme": SourceSelector, "cover_image_url": SourceJSONLD,
+			}),
+		},
+		{
+			slug: "init-jam",
+			want: JamMetadata{
+				Title: "Init Jam", InternalID: "4242", StartDate: "2025-02-20 17:00:00", EndDate: "2025-03-01 17:00:00",
+				SubmissionDate: "2025-03-01 17:00:00", Theme: "Cubes", CoverImageURL: "https://img.itch.zone/init.png",
+			},
+			wantSources: withSources(map[string]string{
+				"title": SourceInitJSON, "internal_id": SourceInitJSON, "start_date": SourceInitJSON, "end_date": SourceInitJSON,
+				"submission_date": SourceInitJSON, "theme": SourceInitJSON, "cover_image_url": SourceInitJSON,
+			}),
+		},
+		{
+			slug: "selector-jam",
+			want: JamMetadata{
+				Title: "Selector Jam", InternalID: "5", StartDate: "Feb 20", EndDate: "Mar 1",
+				Theme: "Shapes", CoverImageURL: "https://img.itch.zone/selector.png",
+			},
+			wantSources: withSources(map[string]string{
+				"title": SourceSelector, "internal_id": SourceScript, "start_date": SourceSelector, "end_date": SourceSelector,
+				"theme": SourceSelector, "cover_image_url": SourceSelector,
+			}),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.slug, func(t *testing.T) {
+			metadata, err := f.FetchJamMetadata(context.Background(), JamRef{Slug: tt.slug})
+			if err != nil {
+				t.Fatalf("FetchJamMetadata: %v", err)
+			}
+
+			want := tt.want
+			want.ID = tt.slug
+			want.Hosts = []Host{{Name: "host", URL: "https://host.itch.io"}}
+			want.SubmissionCount = "120"
+			want.FieldSources = tt.wantSources
+			if !reflect.DeepEqual(*metadata, want) {
+				t.Errorf("metadata =\n%+v\nwant\n%+v", *metadata, want)
+			}
+		})
+	}
+}
+
+func TestExtractInternalIDFromPage(t *testing.T) {
+	tests := []struct {
+		name       string
+		html       string
+		wantID     string
+		wantSource string
+		wantErr    bool
+	}{
+		{
+			name:       "init JSON wins over script text",
+			html:       `<script>new I.ViewJam("#jam", {"jam":{"id":4242}}); var x = {"jam_id": 7};</script>`,
+			wantID:     "4242",
+			wantSource: SourceInitJSON,
+		},
+		{
+			name:       "init_ call without a selector argument",
+			html:       `<script>init_ViewJam({"jam":{"id":"31"}})</script>`,
+			wantID:     "31",
+			wantSource: SourceInitJSON,
+		},
+		{
+			name:       "explicit jam_id in script text",
+			html:       `<script>var config = {"jam_id": 7};</script>`,
+			wantID:     "7",
+			wantSource: SourceScript,
+		},
+		{
+			name:    "other IDs are not guessed at",
+			html:    `<script>var game = {"id": 99, "user_id": 3};</script>`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := goquery.NewDocumentFromReader(strings.NewReader("<html><head>" + tt.html + "</head></html>"))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			sources := fieldSources{}
+			id, err := extractInternalIDFromPage(doc, findInitObject(initObjects(doc), "jam"), sources)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("extractInternalIDFromPage = %q, want an error", id)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("extractInternalIDFromPage: %v", err)
+			}
+			if id != tt.wantID || sources["internal_id"] != tt.wantSource {
+				t.Errorf("extractInternalIDFromPage = %q from %q, want %q from %q", id, sources["internal_id"], tt.wantID, tt.wantSource)
+			}
+		})
+	}
+}
+
+func TestStructuredObjects(t *testing.T) {
+	page := `<html><head>
+<script type="application/ld+json">[{"@type":"Organization","name":"itch.io"},{"@graph":[{"@type":"VideoGame","name":"Alpha"}]}]</script>
+<script type="application/ld+json">{not json</script>
+<script>I.setup(); new I.GamePage("#game", {"game":{"id":101}}); init_Other({"user":{"name":"ann"}});</script>
+</head></html>`
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(page))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The @graph holder is kept along with the objects inside it
+	objects := jsonLDObjects(doc)
+	if len(objects) != 3 {
+		t.Fatalf("jsonLDObjects found %d objects, want 3: %v", len(objects), objects)
+	}
+	if game := findJSONLD(objects, "Product", "VideoGame"); stringField(game, "name") != "Alpha" {
+		t.Errorf("findJSONLD = %v, want the Alpha VideoGame", game)
+	}
+
+	// Calls without a data object are skipped
+	inits := initObjects(doc)
+	if len(inits) != 2 {
+		t.Fatalf("initObjects found %d objects, want 2: %v", len(inits), inits)
+	}
+	if got := stringField(findInitObject(inits, "game"), "id"); got != "101" {
+		t.Errorf("game id = %q, want 101", got)
+	}
+	if got := stringField(findInitObject(inits, "user"), "name"); got != "ann" {
+		t.Errorf("user name = %q, want ann", got)
+	}
+}
+
+func TestFieldSourcesPick(t *testing.T) {
+	tests := []struct {
+		name       string
+		candidates []sourced
+		want       string
+		wantSource string
+	}{
+		{"first non-empty wins", []sourced{{SourceJSONLD, ""}, {SourceInitJSON, "init"}, {SourceSelector, "selector"}}, "init", SourceInitJSON},
+		{"blank values are skipped and kept values trimmed", []sourced{{SourceJSONLD, "  \n"}, {SourceSelector, "  text "}}, "text", SourceSelector},
+		{"no value records no source", []sourced{{SourceJSONLD, ""}, {SourceSelector, " "}}, "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sources := fieldSources{}
+			got := sources.pick("field", tt.candidates...)
+			source, recorded := sources["field"]
+			if got != tt.want || source != tt.wantSource || recorded != (tt.wantSource != "") {
+				t.Errorf("pick = %q from %q, want %q from %q", got, source, tt.want, tt.wantSource)
+			}
+		})
+	}
+}
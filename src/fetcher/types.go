@@ -44,18 +44,19 @@ type Contributor struct {
 
 // JamMetadata represents metadata about a jam
 type JamMetadata struct {
-	ID              string `json:"id"`
-	Title           string `json:"title"`
-	Hosts           []Host `json:"hosts"`
-	StartDate       string `json:"start_date"`
-	EndDate         string `json:"end_date"`
-	SubmissionDate  string `json:"submission_date"`
-	Theme           string `json:"theme"`
-	SubmissionCount string `json:"submission_count"`
-	RatingCount     string `json:"rating_count"`
-	CommentsCount   string `json:"comments_count"`
-	CoverImageURL   string `json:"cover_image_url"`
-	InternalID      string `json:"internal_id"`
+	ID              string            `json:"id"`
+	Title           string            `json:"title"`
+	Hosts           []Host            `json:"hosts"`
+	StartDate       string            `json:"start_date"`
+	EndDate         string            `json:"end_date"`
+	SubmissionDate  string            `json:"submission_date"`
+	Theme           string            `json:"theme"`
+	SubmissionCount string            `json:"submission_count"`
+	RatingCount     string            `json:"rating_count"`
+	CommentsCount   string            `json:"comments_count"`
+	CoverImageURL   string            `json:"cover_image_url"`
+	InternalID      string            `json:"internal_id"`
+	FieldSources    map[string]string `json:"field_sources,omitempty"` // Where each field was found: json-ld, init-json, selector or script
 }
 
 // Host represents a jam host
@@ -80,6 +81,39 @@ type GameSubmission struct {
 	Downloads        []Download        `json:"downloads"`
 	Comments         []Comment         `json:"comments"`
 	CriteriaResponses map[string]string `json:"criteria_responses"`
+	Status           string            `json:"status,omitempty"`
+	Genre            string            `json:"genre,omitempty"`
+	Tags             []string          `json:"tags,omitempty"`
+	MadeWith         []string          `json:"made_with,omitempty"`
+	AverageSession   string            `json:"average_session,omitempty"`
+	Inputs           []string          `json:"inputs,omitempty"`
+	Accessibility    []string          `json:"accessibility,omitempty"`
+	Languages        []string          `json:"languages,omitempty"`
+	License          string            `json:"license,omitempty"`
+	GameInfo         map[string]string `json:"game_info,omitempty"` // Every row of the game page's "More information" table
+	Results          *EntryResult      `json:"results,omitempty"`
+	MediaDir         string            `json:"media_dir,omitempty"` // Shared media directory of the game, relative to the output directory
+	FieldSources     map[string]string `json:"field_sources,omitempty"` // Where each scraped field was found: json-ld, init-json or selector
+	FetchErrors      []string          `json:"fetch_errors,omitempty"` // Requests that still failed after all retries
+	Skipped          []string          `json:"skipped,omitempty"`      // Media and files left out on purpose, such as files over the size budget
+}
+
+// GamePage represents the data scraped from a game's own page
+type GamePage struct {
+	URL            string            `json:"url"`
+	Status         string            `json:"status"`
+	Genre          string            `json:"genre"`
+	Tags           []string          `json:"tags"`
+	MadeWith       []string          `json:"made_with"`
+	AverageSession string            `json:"average_session"`
+	Inputs         []string          `json:"inputs"`
+	Accessibility  []string          `json:"accessibility"`
+	Languages      []string          `json:"languages"`
+	License        string            `json:"license"`
+	Info           map[string]string `json:"info"`
+	FieldSources   map[string]string `json:"field_sources"`
+	Uploads        []Download        `json:"uploads"`
+	CSRFToken      string            `json:"-"`
 }
 
 // CoverImage represents a game's cover image
@@ -94,12 +128,56 @@ type Download struct {
 	Size      string   `json:"size"`
 	Platforms []string `json:"platforms"`
 	UploadDate string  `json:"upload_date"`
+	UploadID  int      `json:"upload_id,omitempty"`
+	URL       string   `json:"url,omitempty"`   // itch.io endpoint that hands out the file's download URL
+	Path      string   `json:"path,omitempty"`  // Local file, relative to the output directory
+	Bytes     int64    `json:"bytes,omitempty"` // Size of the downloaded file
+	SHA256    string   `json:"sha256,omitempty"`
+}
+
+// DownloadResult describes a file written by DownloadFile
+type DownloadResult struct {
+	URL    string
+	Path   string
+	Size   int64
+	SHA256 string
 }
 
 // Comment represents a comment on a game
 type Comment struct {
-	Author    string         `json:"author"`
-	Content   string         `json:"content"`
-	Timestamp string         `json:"timestamp"`
-	Ratings   map[string]int `json:"ratings,omitempty"`
-}
\ No newline at end of file
+	ID          int            `json:"id,omitempty"`
+	ParentID    int            `json:"parent_id,omitempty"` // ID of the post this is a reply to
+	Author      string         `json:"author"`
+	AuthorURL   string         `json:"author_url,omitempty"`
+	IsDeveloper bool           `json:"is_developer"`
+	Content     string         `json:"content"`
+	Timestamp   string         `json:"timestamp"` // RFC 3339, empty if the page only shows a relative date
+	Ratings     map[string]int `json:"ratings,omitempty"`
+}
+
+// JamResults represents the final rankings of a finished jam
+type JamResults struct {
+	JamID    string        `json:"jam_id"`
+	Criteria []string      `json:"criteria"`
+	Entries  []EntryResult `json:"entries"`
+}
+
+// EntryResult represents the overall and per-criteria ranking of one jam entry
+type EntryResult struct {
+	GameID      int               `json:"game_id"` // ID of the game, from the link to its rate page
+	RateURL     string            `json:"rate_url"`
+	Title       string            `json:"title"`
+	Rank        int               `json:"rank"`
+	Score       float64           `json:"score"`
+	RawScore    float64           `json:"raw_score"`
+	RatingCount int               `json:"rating_count"`
+	Criteria    []CriterionResult `json:"criteria,omitempty"`
+}
+
+// CriterionResult represents an entry's ranking for a single criterion such as "Fun"
+type CriterionResult struct {
+	Name     string  `json:"name"`
+	Rank     int     `json:"rank"`
+	Score    float64 `json:"score"`
+	RawScore float64 `json:"raw_score"`
+}
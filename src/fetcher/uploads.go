@@ -0,0 +1,117 @@
+package fetcher
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// uploadURLResponse is the JSON itch.io returns when asked for an upload's download URL
+type uploadURLResponse struct {
+	URL    string   `json:"url"`
+	Errors []string `json:"errors,omitempty"`
+}
+
+// parseUploads extracts the entries of a page's upload list
+func parseUploads(doc *goquery.Document, gameURL string) []Download {
+	var downloads []Download
+
+	doc.Find(".upload_list_widget .upload").Each(func(i int, s *goquery.Selection) {
+		// Prefer the name element's title, which is never truncated
+		name := s.Find(".upload_name .name")
+		download := Download{
+			Filename: strings.TrimSpace(name.AttrOr("title", name.Text())),
+			Size:     strings.TrimSpace(s.Find(".file_size").Text()),
+		}
+		if download.Filename == "" {
+			download.Filename = strings.TrimSpace(s.Find(".upload_name").Text())
+		}
+
+		// Extract platforms
+		s.Find(".download_platforms .platform_tag").Each(func(j int, p *goquery.Selection) {
+			download.Platforms = append(download.Platforms, strings.TrimSpace(p.Text()))
+		})
+
+		// Extract upload date
+		download.UploadDate = strings.TrimSpace(s.Find(".upload_date").Text())
+
+		// The upload ID sits on the download button, or on the upload itself
+		uploadID := s.Find("[data-upload_id]").AttrOr("data-upload_id", s.AttrOr("data-upload_id", ""))
+		download.UploadID, _ = strconv.Atoi(uploadID)
+		if download.UploadID != 0 && gameURL != "" {
+			download.URL = fmt.Sprintf("%s/file/%d", gameURL, download.UploadID)
+		}
+
+		downloads = append(downloads, download)
+	})
+
+	return downloads
+}
+
+// ResolveUploadURL asks itch.io for the short-lived URL an upload can be downloaded from
+func (f *JamFetcher) ResolveUploadURL(ctx context.Context, download Download, csrfToken string) (string, error) {
+	if download.URL == "" {
+		return "", newParseError("", "upload %d has no download endpoint", download.UploadID)
+	}
+
+	endpoint := download.URL + "?source=view_game&as_props=1"
+	resp, err := f.postForm(ctx, endpoint, url.Values{"csrf_token": {csrfToken}})
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to resolve upload %d: %w", download.UploadID, newStatusError(endpoint, resp.StatusCode))
+	}
+
+	var result uploadURLResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", newParseError(endpoint, "failed to decode download URL for upload %d: %w", download.UploadID, err)
+	}
+	if len(result.Errors) > 0 {
+		// itch.io refuses uploads that have to be bought or need a logged-in session
+		return "", fmt.Errorf("itch.io refused upload %d: %s: %w", download.UploadID, strings.Join(result.Errors, "; "), ErrForbidden)
+	}
+	if result.URL == "" {
+		return "", newParseError(endpoint, "no download URL returned for upload %d", download.UploadID)
+	}
+
+	return result.URL, nil
+}
+
+// DownloadUpload follows itch.io's download flow for one upload and saves the file in
+// destDir. The returned result carries the file's size and SHA-256.
+func (f *JamFetcher) DownloadUpload(ctx context.Context, download Download, csrfToken, destDir string) (*DownloadResult, error) {
+	var fileURL string
+	if f.apiKey != "" && download.UploadID != 0 {
+		// With an API key the API hands out the file directly, including restricted uploads
+		fileURL = fmt.Sprintf("%s/uploads/%d/download", f.apiURL, download.UploadID)
+	} else {
+		var err error
+		fileURL, err = f.ResolveUploadURL(ctx, download, csrfToken)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// The resolved URL redirects to the CDN, which the client follows
+	return f.downloadTo(ctx, f.fileClient, fileURL, filepath.Join(destDir, UploadFilename(download)))
+}
+
+// UploadFilename returns a safe local file name for an upload. Names are prefixed with
+// the upload ID, since uploads of one game may share a file name.
+func UploadFilename(download Download) string {
+	name := filepath.Base(strings.ReplaceAll(download.Filename, "\\", "/"))
+	if name == "" || name == "." || name == "/" || name == ".." {
+		return fmt.Sprintf("upload-%d", download.UploadID)
+	}
+	return fmt.Sprintf("%d-%s", download.UploadID, name)
+}
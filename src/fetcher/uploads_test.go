@@ -0,0 +1,183 @@
+package fetcher
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const (
+	testCSRFToken   = "csrf-token"
+	testSession     = "session"
+	testFileContent = "ZIP CONTENTS"
+)
+
+// newUploadServer serves a game page with one upload and itch.io's download flow for
+// it: the page sets a session cookie and a CSRF token, posting both to the upload's
+// file endpoint returns a JSON URL, which redirects to the file on the CDN. The
+// upload endpoint answers with refusal when given, as it does for paid uploads.
+func newUploadServer(t *testing.T, refusal string) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	var server *httptest.Server
+
+	mux.HandleFunc("GET /games/alpha", func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "itchio_token", Value: testSession, Path: "/"})
+		fmt.Fprintf(w, `<html><head><meta name="csrf_token" value="%s"></head><body>
+<div class="upload_list_widget"><div class="upload"><a class="download_btn" data-upload_id="555">Download</a>
+<div class="upload_name"><strong class="name" title="game.zip">game.zip</strong><span class="file_size">12 B</span></div></div></div>
+</body></html>`, testCSRFToken)
+	})
+	mux.HandleFunc("POST /games/alpha/file/555", func(w http.ResponseWriter, r *http.Request) {
+		cookie, err := r.Cookie("itchio_token")
+		if r.PostFormValue("csrf_token") != testCSRFToken || err != nil || cookie.Value != testSession {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		if r.URL.Query().Get("as_props") != "1" {
+			t.Errorf("upload endpoint called without as_props: %s", r.URL)
+		}
+		if refusal != "" {
+			fmt.Fprintf(w, `{"errors":[%q]}`, refusal)
+			return
+		}
+		fmt.Fprintf(w, `{"url":"%s/redirect/555"}`, server.URL)
+	})
+	mux.HandleFunc("GET /redirect/555", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/cdn/555/game.zip", http.StatusFound)
+	})
+	mux.HandleFunc("GET /cdn/555/game.zip", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, testFileContent)
+	})
+	mux.HandleFunc("GET /uploads/555/download", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer api-key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		http.Redirect(w, r, "/cdn/555/game.zip", http.StatusFound)
+	})
+
+	server = httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestDownloadUpload(t *testing.T) {
+	server := newUploadServer(t, "")
+	f := newTestFetcher(t, server.URL)
+	ctx := context.Background()
+
+	page, err := f.FetchGamePage(ctx, server.URL+"/games/alpha")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.CSRFToken != testCSRFToken {
+		t.Errorf("CSRF token = %q, want %q", page.CSRFToken, testCSRFToken)
+	}
+	if len(page.Uploads) != 1 || page.Uploads[0].UploadID != 555 || page.Uploads[0].Filename != "game.zip" {
+		t.Fatalf("uploads = %+v, want game.zip with upload ID 555", page.Uploads)
+	}
+
+	dir := t.TempDir()
+	result, err := f.DownloadUpload(ctx, page.Uploads[0], page.CSRFToken, dir)
+	if err != nil {
+		t.Fatalf("DownloadUpload: %v", err)
+	}
+
+	checkDownload(t, result, filepath.Join(dir, "555-game.zip"))
+}
+
+func TestDownloadUploadWithAPIKey(t *testing.T) {
+	server := newUploadServer(t, "")
+	cfg := testConfig(server.URL)
+	cfg.APIKey = "api-key"
+	cfg.APIURL = server.URL
+	f := NewFetcher(cfg)
+
+	dir := t.TempDir()
+	// With an API key no game page or CSRF token is needed
+	result, err := f.DownloadUpload(context.Background(), Download{UploadID: 555, Filename: "game.zip"}, "", dir)
+	if err != nil {
+		t.Fatalf("DownloadUpload: %v", err)
+	}
+
+	checkDownload(t, result, filepath.Join(dir, "555-game.zip"))
+}
+
+func TestDownloadUploadRefused(t *testing.T) {
+	tests := []struct {
+		name    string
+		refusal string
+		csrf    string
+	}{
+		{"paid upload", "You must buy this game to download it", testCSRFToken},
+		{"wrong CSRF token", "", "stale-token"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newUploadServer(t, tt.refusal)
+			f := newTestFetcher(t, server.URL)
+			ctx := context.Background()
+
+			page, err := f.FetchGamePage(ctx, server.URL+"/games/alpha")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			dir := t.TempDir()
+			_, err = f.DownloadUpload(ctx, page.Uploads[0], tt.csrf, dir)
+			if !errors.Is(err, ErrForbidden) {
+				t.Errorf("error = %v, want %v", err, ErrForbidden)
+			}
+			if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+				t.Errorf("refused download left %d files behind", len(entries))
+			}
+		})
+	}
+}
+
+func TestUploadFilename(t *testing.T) {
+	tests := []struct {
+		download Download
+		want     string
+	}{
+		{Download{UploadID: 555, Filename: "game.zip"}, "555-game.zip"},
+		{Download{UploadID: 556, Filename: "game.zip"}, "556-game.zip"}, // Same name, other upload
+		{Download{UploadID: 7, Filename: "../../etc/passwd"}, "7-passwd"},
+		{Download{UploadID: 8, Filename: `C:\builds\game.exe`}, "8-game.exe"},
+		{Download{UploadID: 9, Filename: ""}, "upload-9"},
+		{Download{UploadID: 10, Filename: ".."}, "upload-10"},
+	}
+
+	for _, tt := range tests {
+		if got := UploadFilename(tt.download); got != tt.want {
+			t.Errorf("UploadFilename(%q, %d) = %q, want %q", tt.download.Filename, tt.download.UploadID, got, tt.want)
+		}
+	}
+}
+
+// checkDownload checks that a download wrote the test file to path
+func checkDownload(t *testing.T, result *DownloadResult, path string) {
+	t.Helper()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("downloaded file: %v", err)
+	}
+	if string(data) != testFileContent {
+		t.Errorf("file content = %q, want %q", data, testFileContent)
+	}
+
+	sum := sha256.Sum256([]byte(testFileContent))
+	if result.Path != path || result.Size != int64(len(testFileContent)) || result.SHA256 != hex.EncodeToString(sum[:]) {
+		t.Errorf("result = %+v, want %s of %d bytes with SHA-256 %x", result, path, len(testFileContent), sum)
+	}
+}
@@ -0,0 +1,10 @@
+//go:build !linux && !darwin && !freebsd && !dragonfly && !windows
+
+package fsutil
+
+import "errors"
+
+// FreeSpace is not supported on this platform and always returns an error
+func FreeSpace(path string) (int64, error) {
+	return 0, errors.New("free space is not available on this platform")
+}
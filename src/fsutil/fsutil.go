@@ -0,0 +1,93 @@
+// Package fsutil provides crash-safe file writing shared by the fetcher and storage packages
+package fsutil
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// CreateTemp creates a temporary file next to destPath, so it can later be renamed over it
+func CreateTemp(destPath string) (*os.File, error) {
+	dir := filepath.Dir(destPath)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	return os.CreateTemp(dir, "."+filepath.Base(destPath)+".tmp-*")
+}
+
+// Commit flushes a temporary file to disk, closes it and renames it to destPath.
+// The temporary file is removed if any step fails.
+func Commit(file *os.File, destPath string) error {
+	tmpPath := file.Name()
+
+	if err := file.Sync(); err != nil {
+		file.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := file.Close(); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := os.Chmod(tmpPath, 0644); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := os.Rename(tmpPath, destPath); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+
+	syncDir(filepath.Dir(destPath))
+	return nil
+}
+
+// Discard closes and removes a temporary file that will not be committed
+func Discard(file *os.File) {
+	file.Close()
+	os.Remove(file.Name())
+}
+
+// WriteFile atomically replaces the file at path with data: readers see either
+// the old content or the new one, never a partially written file
+func WriteFile(path string, data []byte) error {
+	file, err := CreateTemp(path)
+	if err != nil {
+		return err
+	}
+
+	if _, err := file.Write(data); err != nil {
+		Discard(file)
+		return err
+	}
+
+	return Commit(file, path)
+}
+
+// syncDir flushes a directory entry so a rename survives a crash. Not every
+// platform supports syncing directories, so failures are ignored.
+func syncDir(dir string) {
+	d, err := os.Open(dir)
+	if err != nil {
+		return
+	}
+	d.Sync()
+	d.Close()
+}
+
+// ExistingAncestor returns path, or its nearest parent directory that exists when path
+// has not been created yet
+func ExistingAncestor(path string) string {
+	path = filepath.Clean(path)
+	for {
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+		parent := filepath.Dir(path)
+		if parent == path {
+			return path
+		}
+		path = parent
+	}
+}
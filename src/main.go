@@ -1,85 +1,151 @@
 package main
 
 import (
+	"errors"
 	"flag"
 	"fmt"
 	"log"
+	"os"
 	"strings"
-	"sync"
 
 	"Itchalyser/config"
-	"Itchalyser/fetcher"
-	"Itchalyser/processor"
 	"Itchalyser/storage"
 )
 
+// Exit codes, so scripts and schedulers can tell how a command went
+const (
+	exitOK         = 0 // Every jam and game finished
+	exitFailed     = 1 // Nothing was processed, or the configuration is invalid
+	exitPartial    = 2 // Finished, but some jams or games failed, or verify found problems
+	exitIncomplete = 3 // Interrupted by a signal, stopped by a run budget or by rate limiting
+)
+
+// command is a subcommand of the tool
+type command struct {
+	name    string
+	summary string
+	run     func(args []string) int
+}
+
+// commands lists the subcommands. Only scrape uses the network; the others work on an
+// existing output directory.
+var commands = []command{
+	{"scrape", "Scrape jams from itch.io into the output directory", runScrape},
+	{"report", "Generate markdown and HTML reports of scraped jams", runReport},
+	{"export", "Export the submissions of scraped jams as CSV, JSON Lines or JSON", runExport},
+	{"stats", "Print statistics of scraped jams", runStats},
+	{"verify", "Check scraped files against their recorded sizes and checksums", runVerify},
+	{"serve", "Browse scraped jams in a web browser", runServe},
+}
+
 func main() {
-	// Default user agent
-	DefaultUserAgent := "Itchalyser/1.0 (https://github.com/Abstractmelon/Itchalyser)"
-
-	// Parse command line flags
-	jamURLs := flag.String("jam", "", "Comma-separated list of jam URLs")
-	outputFormat := flag.String("output", "json", "Output format (json, jsonl, markdown)")
-	outputDir := flag.String("dir", "../data", "Directory to store output")
-	workers := flag.Int("workers", 2, "Number of concurrent workers")
-	userAgent := flag.String("user-agent", DefaultUserAgent, "User agent string for HTTP requests")
-	requestDelay := flag.Int("delay", 1500, "Delay between requests in milliseconds (default: 1500)")
-	downloadMedia := flag.Bool("media", true, "Download media files")
-	downloadGames := flag.Bool("games", false, "Download game files")
-	flag.Parse()
-
-	if *jamURLs == "" {
-		log.Fatal("Please provide at least one jam URL using the -jam flag")
+	args := os.Args[1:]
+
+	// Without a command, flags are those of scrape, as before subcommands existed
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		if len(args) == 0 {
+			printUsage()
+			os.Exit(exitFailed)
+		}
+		os.Exit(runScrape(args))
+	}
+
+	for _, cmd := range commands {
+		if cmd.name == args[0] {
+			os.Exit(cmd.run(args[1:]))
+		}
+	}
+
+	if args[0] != "help" {
+		fmt.Fprintf(os.Stderr, "Unknown command: %s\n\n", args[0])
+	}
+	printUsage()
+	os.Exit(exitFailed)
+}
+
+// printUsage lists the commands
+func printUsage() {
+	fmt.Fprintln(os.Stderr, "Usage: Itchalyser <command> [flags]")
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "Commands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  %-8s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "Run 'Itchalyser <command> -h' for the flags of a command.")
+}
+
+// archiveFlags are the flags of the commands that read an existing output directory
+type archiveFlags struct {
+	dir     *string
+	jams    *string
+	backend *string
+}
+
+// addArchiveFlags adds the flags for reading an output directory to a flag set
+func addArchiveFlags(fs *flag.FlagSet) archiveFlags {
+	return archiveFlags{
+		dir:     fs.String("dir", "../data", "Output directory of earlier scrapes"),
+		jams:    fs.String("jam", "", "Comma-separated list of jam slugs (default: every jam in the output directory)"),
+		backend: addBackendFlag(fs),
+	}
+}
+
+// addBackendFlag adds the flag choosing the storage backend to read to a flag set
+func addBackendFlag(fs *flag.FlagSet) *string {
+	return fs.String("backend", config.BackendFiles, "Storage backend the jams were scraped into: "+strings.Join(config.Backends, ", "))
+}
+
+// openBackend opens the output directory and its storage backend for reading
+func openBackend(dir, kind string) (*storage.Manager, storage.Backend) {
+	store := storage.NewManager(dir)
+	backend, err := storage.OpenBackend(kind, store, true)
+	if errors.Is(err, os.ErrNotExist) {
+		log.Fatalf("No scraped jams found in %s: %v", dir, err)
 	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	return store, backend
+}
 
-	// Initialize configuration
-	cfg := config.Config{
-		OutputFormat:  *outputFormat,
-		OutputDir:     *outputDir,
-		Workers:       *workers,
-		UserAgent:     *userAgent,
-		RequestDelay:  *requestDelay,
-		DownloadMedia: *downloadMedia,
-		DownloadGames: *downloadGames,
+// jamIDs returns the jams selected with -jam, or nil for every jam
+func (a archiveFlags) jamIDs() []string {
+	var jamIDs []string
+	for _, jamID := range strings.Split(*a.jams, ",") {
+		if jamID = strings.TrimSpace(jamID); jamID != "" {
+			jamIDs = append(jamIDs, jamID)
+		}
 	}
+	return jamIDs
+}
+
+// open opens the selected jams through the storage loader shared by all offline
+// commands. Submissions are read later, one at a time. The backend is closed by the
+// caller.
+func (a archiveFlags) open() (*storage.Manager, storage.Backend, []*storage.Jam) {
+	store, backend := openBackend(*a.dir, *a.backend)
 
-	// Create storage manager
-	store := storage.NewManager(cfg.OutputDir)
-
-	// Initialize jam processor
-	proc := processor.NewProcessor(store, cfg)
-
-	// Process each jam URL
-	urls := strings.Split(*jamURLs, ",")
-	var wg sync.WaitGroup
-	semaphore := make(chan struct{}, cfg.Workers)
-
-	for _, url := range urls {
-		wg.Add(1)
-		semaphore <- struct{}{} // Acquire semaphore
-		
-		go func(jamURL string) {
-			defer wg.Done()
-			defer func() { <-semaphore }() // Release semaphore
-			
-			jamURL = strings.TrimSpace(jamURL)
-			
-			// Extract jam ID from URL
-			jamID, err := fetcher.ExtractJamID(jamURL)
-			if err != nil {
-				log.Printf("Error extracting jam ID from %s: %v", jamURL, err)
-				return
-			}
-			
-			fmt.Printf("Processing jam: %s (ID: %s)\n", jamURL, jamID)
-			
-			// Process jam
-			if err := proc.ProcessJam(jamID); err != nil {
-				log.Printf("Error processing jam %s: %v", jamID, err)
-			}
-		}(url)
+	jams, err := storage.OpenJams(backend, a.jamIDs())
+	if err != nil {
+		log.Fatal(err)
 	}
+	if len(jams) == 0 {
+		log.Fatalf("No scraped jams found in %s", *a.dir)
+	}
+	return store, backend, jams
+}
 
-	wg.Wait()
-	fmt.Println("All jams processed successfully!")
+// formatBytes formats a byte count for humans
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
 }
@@ -0,0 +1,88 @@
+package processor
+
+import (
+	"errors"
+	"fmt"
+	"log"
+
+	"Itchalyser/fetcher"
+)
+
+// Each kind of fetcher error calls for its own reaction:
+//
+//   - not found: the game or file was removed, so it is skipped and not retried
+//   - forbidden: the page or file needs a logged-in session; files are skipped, pages fail
+//   - rate limited: itch.io is still throttling after every retry, so the run stops
+//     like an exhausted budget and can be resumed later
+//   - transient: the game fails and is tried again by a resumed run
+//   - parse: the game fails and the jam's summary warns that the layout may have changed
+
+// loginHint is added to errors of pages and files that need a logged-in session
+const loginHint = "needs a logged-in session, see -cookies and -api-key"
+
+// noteFailure records the run-wide effects of a failed request: rate limiting that
+// outlasted the retries stops the run, and unreadable responses are counted
+func (p *Processor) noteFailure(err error) {
+	switch {
+	case errors.Is(err, fetcher.ErrRateLimited):
+		p.rateLimitMutex.Lock()
+		if p.rateLimitErr == nil {
+			p.rateLimitErr = err
+		}
+		p.rateLimitMutex.Unlock()
+	case errors.Is(err, fetcher.ErrParse):
+		p.parseErrors.Add(1)
+	}
+}
+
+// stopErr returns why the run has to stop before it is finished: a run budget that ran
+// out, or itch.io still rate limiting after every retry. It returns nil otherwise.
+func (p *Processor) stopErr() error {
+	if err := p.fetcher.BudgetErr(); err != nil {
+		return err
+	}
+
+	p.rateLimitMutex.Lock()
+	defer p.rateLimitMutex.Unlock()
+	return p.rateLimitErr
+}
+
+// isStopErr reports whether an error stopped the run early rather than failing it
+func isStopErr(err error) bool {
+	return errors.Is(err, fetcher.ErrBudgetExhausted) || errors.Is(err, fetcher.ErrRateLimited)
+}
+
+// pageFailed records a failed page request of a game as a fetch error
+func (p *Processor) pageFailed(game *fetcher.GameSubmission, gameID, label string, err error) {
+	p.noteFailure(err)
+
+	message := fmt.Sprintf("%s: %v", label, err)
+	switch {
+	case errors.Is(err, fetcher.ErrForbidden):
+		message += " (" + loginHint + ")"
+	case errors.Is(err, fetcher.ErrTransient):
+		message += " (retried by -resume)"
+	}
+
+	log.Printf("Warning: Failed to fetch %s for game %s: %v", label, gameID, err)
+	game.FetchErrors = append(game.FetchErrors, message)
+}
+
+// downloadFailed records a failed media or file download of a game. Files that are too
+// large, no longer exist or need a logged-in session are skipped, since retrying cannot
+// fetch them; other failures are fetch errors.
+func (p *Processor) downloadFailed(game *fetcher.GameSubmission, gameID, label string, err error) {
+	p.noteFailure(err)
+
+	switch {
+	case errors.Is(err, fetcher.ErrFileTooLarge), errors.Is(err, fetcher.ErrNotFound):
+		log.Printf("Skipping %s of game %s: %v", label, gameID, err)
+		game.Skipped = append(game.Skipped, fmt.Sprintf("%s: %v", label, err))
+	case errors.Is(err, fetcher.ErrForbidden):
+		log.Printf("Skipping %s of game %s: %v", label, gameID, err)
+		game.Skipped = append(game.Skipped, fmt.Sprintf("%s: %v (%s)", label, err, loginHint))
+	default:
+		log.Printf("Warning: Failed to download %s for game %s: %v", label, gameID, err)
+		game.FetchErrors = append(game.FetchErrors, fmt.Sprintf("%s: %v", label, err))
+	}
+}
@@ -0,0 +1,408 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"Itchalyser/fetcher"
+	"Itchalyser/storage"
+)
+
+// The games of a jam flow through a pipeline of stages joined by bounded channels:
+//
+//	entries -> details -> save -> media -> output
+//
+// Each stage has its own pool of workers. A stage whose output channel is full blocks,
+// which holds back the stages before it, so a slow CDN cannot make the scraper run
+// arbitrarily far ahead and a game with many screenshots no longer occupies a scraping
+// worker while its media downloads.
+
+// gameJob is a game moving through the pipeline
+type gameJob struct {
+	index      int
+	entry      fetcher.JamGame
+	gameID     string
+	status     storage.GameStatus // Crawl status at the start of the run
+	submission *fetcher.GameSubmission
+	page       *fetcher.GamePage
+	cached     cachedGame // Game-level data already fetched for another jam
+	fetched    bool       // Whether details were scraped in this run rather than loaded
+	unchanged  bool       // Whether an incremental scrape found the entry unchanged
+	gone       bool       // Whether the entry's rate page no longer exists
+	started    time.Time
+	stats      fetcher.RequestStats // Requests made for this game
+}
+
+// jamRun holds the state shared by the stages while one jam is processed
+type jamRun struct {
+	p             *Processor
+	jamID         string
+	state         *storage.CrawlState
+	resultsByGame map[int]*fetcher.EntryResult
+	submissions   []*fetcher.GameSubmission // Collected for the markdown report, in entry order
+	games         []*storage.GameReport     // Report of each selected game, in entry order
+
+	// Counters for the summary
+	unchangedCount, finishedCount, failedCount, skippedCount, goneCount atomic.Int64
+}
+
+// runStage starts workers goroutines that pass each job from in through fn and send the
+// jobs fn accepts to out, which may be nil for the last stage. Jobs are dropped once
+// the context is cancelled, but in is still drained so earlier stages can finish. out
+// is closed when all workers are done. The returned WaitGroup completes at the same time.
+func runStage(ctx context.Context, workers int, in <-chan *gameJob, out chan<- *gameJob, fn func(context.Context, *gameJob) bool) *sync.WaitGroup {
+	if workers < 1 {
+		workers = 1
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range in {
+				if ctx.Err() != nil || !fn(ctx, job) {
+					continue
+				}
+				if out != nil {
+					out <- job
+				}
+			}
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		if out != nil {
+			close(out)
+		}
+	}()
+
+	return &wg
+}
+
+// run processes the given entries through the pipeline and waits until every stage is done.
+// When a run budget runs out or itch.io keeps rate limiting, the pipeline stops as if
+// interrupted and ctx's cause is the error that stopped it.
+func (r *jamRun) run(ctx context.Context, entries []fetcher.JamGame) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	// A job that ran into an exhausted budget or rate limiting is left for a later run,
+	// like an interrupted one
+	guard := func(fn func(context.Context, *gameJob) bool) func(context.Context, *gameJob) bool {
+		return func(ctx context.Context, job *gameJob) bool {
+			ok := fn(ctx, job)
+			if err := r.p.stopErr(); err != nil {
+				cancel(err)
+				return false
+			}
+			return ok
+		}
+	}
+
+	cfg := r.p.config
+	queue := cfg.QueueSize
+	if queue < 0 {
+		queue = 0
+	}
+
+	entryCh := make(chan *gameJob, queue)
+	detailsCh := make(chan *gameJob, queue)
+	savedCh := make(chan *gameJob, queue)
+	mediaCh := make(chan *gameJob, queue)
+
+	runStage(ctx, cfg.Workers, entryCh, detailsCh, guard(r.fetchDetails))
+	runStage(ctx, cfg.WriteWorkers, detailsCh, savedCh, r.saveDetails)
+	runStage(ctx, cfg.MediaWorkers, savedCh, mediaCh, guard(r.downloadMedia))
+	output := runStage(ctx, cfg.WriteWorkers, mediaCh, nil, r.writeOutput)
+
+	r.feedEntries(ctx, entries, entryCh)
+	close(entryCh)
+
+	// Each stage closes its output once its input is drained, so the last stage
+	// finishes only after every stage before it
+	output.Wait()
+}
+
+// feedEntries is the entries stage. It turns each jam entry into a job, skipping games
+// finished in an earlier run, and stops feeding when the context is cancelled.
+func (r *jamRun) feedEntries(ctx context.Context, entries []fetcher.JamGame, out chan<- *gameJob) {
+	for i, entry := range entries {
+		gameID := strconv.Itoa(entry.Game.ID)
+
+		// Skip games finished in an earlier run, but keep them in this run's outputs
+		status := r.state.Status(gameID)
+		if status == storage.StatusMediaDone {
+			log.Printf("Skipping game %s as it was finished in an earlier run", gameID)
+			r.p.reuseFinishedGame(r.jamID, gameID, i, r.submissions)
+			r.skippedCount.Add(1)
+			r.games[i].Outcome = storage.OutcomeSkipped
+			continue
+		}
+
+		// Removed games are kept with the data from the entry list only
+		if status == storage.StatusGone {
+			log.Printf("Skipping game %s as it was removed from itch.io", gameID)
+			r.p.reuseFinishedGame(r.jamID, gameID, i, r.submissions)
+			r.goneCount.Add(1)
+			r.games[i].Outcome = storage.OutcomeGone
+			continue
+		}
+
+		job := &gameJob{
+			index:      i,
+			entry:      entry,
+			gameID:     gameID,
+			status:     status,
+			submission: newSubmission(entry, r.resultsByGame[entry.Game.ID]),
+		}
+
+		select {
+		case out <- job:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// newSubmission creates the basic game submission from a jam entry
+func newSubmission(jg fetcher.JamGame, results *fetcher.EntryResult) *fetcher.GameSubmission {
+	submission := &fetcher.GameSubmission{
+		ID:          strconv.Itoa(jg.Game.ID),
+		Title:       jg.Game.Title,
+		URL:         jg.Game.URL,
+		Platforms:   jg.Game.Platforms,
+		CreatedAt:   jg.CreatedAt,
+		Coolness:    jg.Coolness,
+		RatingCount: jg.RatingCount,
+		Cover: fetcher.CoverImage{
+			URL:   jg.Game.Cover,
+			Color: jg.Game.CoverColor,
+		},
+		Results: results,
+	}
+
+	// Add authors
+	submission.Authors = append(submission.Authors, jg.Game.User)
+
+	// Add contributors if available
+	for _, contributor := range jg.Contributors {
+		submission.Authors = append(submission.Authors, fetcher.User{
+			Name: contributor.Name,
+			URL:  contributor.URL,
+		})
+	}
+
+	return submission
+}
+
+// fetchDetails is the details stage. It scrapes the game's rate page and game page, or
+// reuses details stored by an earlier run or fetched for another jam.
+func (r *jamRun) fetchDetails(ctx context.Context, job *gameJob) bool {
+	p := r.p
+	gameID := job.gameID
+	submission := job.submission
+	log.Printf("Starting processing for game: %s - %s", gameID, submission.Title)
+
+	// Until the output stage records the outcome, a game that is dropped was interrupted
+	job.started = time.Now()
+	r.games[job.index].Outcome = storage.OutcomeInterrupted
+	ctx = fetcher.WithStats(ctx, &job.stats)
+
+	job.cached = p.cachedGame(gameID)
+	// A game resumed from saved details keeps its status, so a run interrupted during
+	// its media does not send it back to fetching details
+	if job.status != storage.StatusDetailsFetched {
+		r.state.Update(gameID, storage.StatusPending, nil)
+	}
+
+	// In incremental mode, entries whose stats did not change since the stored
+	// game.json keep their details; only the fields from the entry are refreshed
+	var previous *fetcher.GameSubmission
+	if p.config.Incremental {
+		if stored, err := p.storage.LoadGameSubmission(r.jamID, gameID); err == nil && entryUnchanged(stored, job.entry) {
+			previous = stored
+			job.unchanged = true
+		}
+	}
+
+	// Reuse details saved by an interrupted run
+	if previous == nil && job.status == storage.StatusDetailsFetched {
+		previous, _ = p.storage.LoadGameSubmission(r.jamID, gameID)
+	}
+
+	if previous != nil {
+		applyDetails(submission, previous)
+		if job.unchanged {
+			r.unchangedCount.Add(1)
+			log.Printf("Game %s unchanged since last scrape, refreshing stats only", gameID)
+		} else {
+			log.Printf("Reusing saved details for game: %s", gameID)
+		}
+		return true
+	}
+
+	job.fetched = true
+	gameDetails, err := p.fetcher.FetchGameDetails(ctx, r.jamID, gameID)
+	if gameDetails == nil && errors.Is(err, fetcher.ErrNotFound) {
+		// The entry was removed; there is no game page or media left to fetch
+		log.Printf("Game %s no longer exists: %v", gameID, err)
+		submission.Skipped = append(submission.Skipped, fmt.Sprintf("%s: %v", goneLabel, err))
+		job.gone = true
+		return ctx.Err() == nil
+	}
+	// A failed comment page still returns the rest of the details
+	if gameDetails != nil {
+		applyDetails(submission, gameDetails)
+		markDeveloperComments(submission)
+		log.Printf("Fetched additional details for game: %s", gameID)
+	}
+	if err != nil {
+		p.pageFailed(submission, gameID, "details", err)
+		if isStopErr(err) {
+			return false
+		}
+	}
+
+	// The game's own page carries tags, engine and the upload list
+	if job.cached.page != nil {
+		job.page = job.cached.page
+		applyGamePage(submission, job.cached.page)
+		log.Printf("Reusing game page of game %s from another jam", gameID)
+	} else if p.config.FetchGamePage || p.config.DownloadGames {
+		if page, err := p.fetcher.FetchGamePage(ctx, submission.URL); err != nil {
+			p.pageFailed(submission, gameID, "game page", err)
+		} else {
+			job.page = page
+			applyGamePage(submission, page)
+			p.updateCachedGame(gameID, func(c *cachedGame) { c.page = page })
+			log.Printf("Fetched game page for game: %s", gameID)
+		}
+	}
+
+	// Interrupted: leave the game for a resumed run instead of saving partial data.
+	// The crawl state already records the last step that completed.
+	return ctx.Err() == nil
+}
+
+// saveDetails is the save stage. It checkpoints freshly scraped details so a resumed
+// run can go straight to media.
+func (r *jamRun) saveDetails(ctx context.Context, job *gameJob) bool {
+	if !job.fetched || job.gone || len(job.submission.FetchErrors) > 0 {
+		return true
+	}
+
+	if err := r.p.storage.SaveGameSubmission(r.jamID, job.gameID, job.submission); err != nil {
+		log.Printf("Warning: Failed to save game submission %s: %v", job.gameID, err)
+	}
+	r.p.updateState(r.state, job.gameID, storage.StatusDetailsFetched, nil)
+	return true
+}
+
+// downloadMedia is the media stage. It downloads the game's media and files into the
+// game's shared directory, unless they were downloaded for another jam already.
+func (r *jamRun) downloadMedia(ctx context.Context, job *gameJob) bool {
+	p := r.p
+	gameID := job.gameID
+	submission := job.submission
+	ctx = fetcher.WithStats(ctx, &job.stats)
+
+	if job.gone {
+		return true
+	}
+
+	// Unchanged games already have their media, and media is shared by every jam the game is in
+	if p.config.DownloadMedia {
+		if !job.unchanged && !job.cached.mediaDone {
+			log.Printf("Downloading media for game: %s", gameID)
+			errorCount := len(submission.FetchErrors)
+			p.downloadGameMedia(ctx, gameID, submission)
+			if len(submission.FetchErrors) == errorCount && ctx.Err() == nil {
+				p.updateCachedGame(gameID, func(c *cachedGame) { c.mediaDone = true })
+			}
+		}
+		// Only point at media that has a manifest, which verify checks against
+		if _, err := p.storage.LoadMediaManifest(gameID); err == nil {
+			submission.MediaDir = p.files.RelPath(p.files.MediaDir(gameID))
+		}
+	}
+
+	// Download game files if configured
+	if p.config.DownloadGames && !job.unchanged {
+		if job.cached.filesDone {
+			mergeUploads(submission, job.cached.downloads)
+			log.Printf("Reusing game files of game %s from another jam", gameID)
+		} else {
+			log.Printf("Downloading game files for game: %s", gameID)
+			errorCount := len(submission.FetchErrors)
+			p.downloadGameFiles(ctx, gameID, submission, job.page)
+			if len(submission.FetchErrors) == errorCount && ctx.Err() == nil {
+				downloads := append([]fetcher.Download(nil), submission.Downloads...)
+				p.updateCachedGame(gameID, func(c *cachedGame) {
+					c.filesDone = true
+					c.downloads = downloads
+				})
+			}
+		}
+	}
+
+	return ctx.Err() == nil
+}
+
+// writeOutput is the output stage. It writes the finished submission to every requested
+// output, including any failed requests, and records the game's final crawl status.
+func (r *jamRun) writeOutput(ctx context.Context, job *gameJob) bool {
+	gameID := job.gameID
+	submission := job.submission
+
+	report := r.games[job.index]
+	report.Errors = submission.FetchErrors
+	report.Skipped = submission.Skipped
+	report.Requests = job.stats.Requests()
+	report.Retries = job.stats.Retries()
+	report.BytesDownloaded = job.stats.Bytes()
+	report.DurationSeconds = time.Since(job.started).Seconds()
+
+	if err := r.p.writeSubmission(r.jamID, gameID, submission); err != nil {
+		log.Printf("Warning: Failed to save game submission %s: %v", gameID, err)
+		r.p.updateState(r.state, gameID, storage.StatusFailed, err)
+		r.failedCount.Add(1)
+		report.Outcome = storage.OutcomeFailed
+		report.Errors = append(report.Errors, fmt.Sprintf("save: %v", err))
+		return true
+	}
+	if r.submissions != nil {
+		r.submissions[job.index] = submission
+	}
+	log.Printf("Saved game submission for game: %s", gameID)
+
+	switch {
+	case len(submission.FetchErrors) > 0:
+		r.p.updateState(r.state, gameID, storage.StatusFailed, errors.New(strings.Join(submission.FetchErrors, "; ")))
+		r.failedCount.Add(1)
+		report.Outcome = storage.OutcomeFailed
+	case job.gone:
+		r.p.updateState(r.state, gameID, storage.StatusGone, nil)
+		r.goneCount.Add(1)
+		report.Outcome = storage.OutcomeGone
+	case job.unchanged:
+		r.p.updateState(r.state, gameID, storage.StatusMediaDone, nil)
+		r.finishedCount.Add(1)
+		report.Outcome = storage.OutcomeUnchanged
+	default:
+		r.p.updateState(r.state, gameID, storage.StatusMediaDone, nil)
+		r.finishedCount.Add(1)
+		report.Outcome = storage.OutcomeDone
+	}
+
+	log.Printf("Finished processing game: %s - %s", gameID, submission.Title)
+	return true
+}
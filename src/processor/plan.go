@@ -0,0 +1,176 @@
+package processor
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"Itchalyser/config"
+	"Itchalyser/fetcher"
+	"Itchalyser/storage"
+)
+
+// Plan estimates what processing a jam would cost. Counts are lower bounds: comment and
+// results pagination is only discovered while scraping, and screenshots and file sizes
+// are only known for games stored by an earlier run.
+type Plan struct {
+	JamID               string  `json:"jam_id"`
+	Title               string  `json:"title"`
+	Entries             int     `json:"entries"`               // Entries in the jam
+	Selected            int     `json:"selected"`              // Entries left after filters, sampling and limit
+	Skipped             int     `json:"skipped"`               // Selected entries finished by an earlier run (-resume)
+	Unchanged           int     `json:"unchanged"`             // Selected entries unchanged since the last scrape (-incremental)
+	PageRequests        int     `json:"page_requests"`         // HTML and API requests to itch.io
+	MediaDownloads      int     `json:"media_downloads"`       // Cover and screenshot downloads
+	FileDownloads       int     `json:"file_downloads"`        // Game file downloads
+	KnownBytes          int64   `json:"known_bytes"`           // Download volume of the media and files with a known size
+	UnknownSizes        int     `json:"unknown_sizes"`         // Downloads whose size is not known
+	UnknownGames        int     `json:"unknown_games"`         // Games whose screenshots and uploads are not known yet
+	PageSeconds         float64 `json:"page_seconds"`          // Minimum time the itch.io rate limit allows for the page requests
+	DownloadSeconds     float64 `json:"download_seconds"`      // Minimum time the CDN rate limit allows for the downloads
+	EstimatedRunSeconds float64 `json:"estimated_run_seconds"` // Page and download time overlap in the pipeline, so the longer one
+}
+
+// PlanJam estimates the requests, time and download volume of processing a jam with
+// the current configuration. It only fetches the jam's metadata and entry list and
+// reads what earlier runs stored; nothing is written to disk. Games planned for an
+// earlier jam of the same run are counted without their game page and downloads,
+// as ProcessJam shares those between jams.
+func (p *Processor) PlanJam(ctx context.Context, jam fetcher.JamRef) (*Plan, error) {
+	jamID := jam.Key()
+
+	metadata, err := p.fetcher.FetchJamMetadata(ctx, jam)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch jam metadata: %w", err)
+	}
+
+	entriesResponse, err := p.fetcher.JamEntries(ctx, jam)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch jam entries: %w", err)
+	}
+
+	entries, err := selectEntries(entriesResponse.JamGames, p.config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to select entries: %w", err)
+	}
+
+	plan := &Plan{
+		JamID:    jamID,
+		Title:    metadata.Title,
+		Entries:  len(entriesResponse.JamGames),
+		Selected: len(entries),
+	}
+
+	// The jam page, results and cover; results pagination and criteria pages are not known yet
+	if p.config.FetchResults {
+		plan.PageRequests++
+	}
+	if p.config.DownloadMedia && metadata.CoverImageURL != "" {
+		plan.MediaDownloads++
+		plan.UnknownSizes++
+	}
+
+	state := storage.NewCrawlState(jamID)
+	if p.config.Resume {
+		if state, err = p.files.LoadCrawlState(jamID); err != nil {
+			return nil, fmt.Errorf("failed to load crawl state: %w", err)
+		}
+	}
+
+	if p.plannedGames == nil {
+		p.plannedGames = make(map[string]bool)
+	}
+
+	for _, entry := range entries {
+		gameID := strconv.Itoa(entry.Game.ID)
+
+		if state.Status(gameID) == storage.StatusMediaDone {
+			plan.Skipped++
+			continue
+		}
+
+		stored, _ := p.storage.LoadGameSubmission(jamID, gameID)
+		if p.config.Incremental && stored != nil && entryUnchanged(stored, entry) {
+			plan.Unchanged++
+			continue
+		}
+
+		// The rate page is jam-specific and always fetched
+		plan.PageRequests++
+
+		if p.plannedGames[gameID] {
+			continue
+		}
+		p.plannedGames[gameID] = true
+
+		if p.config.FetchGamePage || p.config.DownloadGames {
+			plan.PageRequests++
+		}
+		if stored == nil && (p.config.DownloadMedia || p.config.DownloadGames) {
+			plan.UnknownGames++
+		}
+
+		if p.config.DownloadMedia {
+			p.planMedia(plan, gameID, entry, stored)
+		}
+		if p.config.DownloadGames && stored != nil {
+			for _, download := range stored.Downloads {
+				// Each file needs a request for its download URL before the download itself
+				plan.PageRequests++
+				plan.FileDownloads++
+				if size, ok := parseSize(download.Size); ok {
+					plan.KnownBytes += size
+				} else {
+					plan.UnknownSizes++
+				}
+			}
+		}
+	}
+
+	pageTime, downloadTime := p.fetcher.EstimateRequestTime(plan.PageRequests, plan.MediaDownloads+plan.FileDownloads)
+	plan.PageSeconds = pageTime.Seconds()
+	plan.DownloadSeconds = downloadTime.Seconds()
+	plan.EstimatedRunSeconds = max(pageTime, downloadTime).Seconds()
+
+	log.Printf("Planned jam %s: %d of %d entries selected", jamID, plan.Selected, plan.Entries)
+	return plan, nil
+}
+
+// planMedia counts a game's cover and screenshots, taking sizes from the media manifest
+// of an earlier run where there is one
+func (p *Processor) planMedia(plan *Plan, gameID string, entry fetcher.JamGame, stored *fetcher.GameSubmission) {
+	sizes := make(map[string]int64)
+	if manifest, err := p.storage.LoadMediaManifest(gameID); err == nil {
+		for _, file := range manifest.Files {
+			sizes[file.URL] = file.Size
+		}
+	}
+
+	urls := []string{entry.Game.Cover}
+	if stored != nil {
+		urls = append(urls, stored.Screenshots...)
+	}
+
+	for _, url := range urls {
+		if url == "" {
+			continue
+		}
+		plan.MediaDownloads++
+		if size, ok := sizes[url]; ok {
+			plan.KnownBytes += size
+		} else {
+			plan.UnknownSizes++
+		}
+	}
+}
+
+// parseSize parses a file size as shown on itch.io's upload list, such as "12 B" or "1.5 MB"
+func parseSize(size string) (int64, bool) {
+	if strings.TrimSpace(size) == "" {
+		return 0, false
+	}
+	n, err := config.ParseByteSize(size)
+	return n, err == nil
+}
@@ -1,56 +1,119 @@
 package processor
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
+	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"Itchalyser/config"
 	"Itchalyser/fetcher"
 	"Itchalyser/storage"
 )
 
+// stateSaveInterval is the number of crawl state changes after which the state is flushed to disk
+const stateSaveInterval = 20
+
 // Processor handles the processing of jams and games
 type Processor struct {
 	fetcher         *fetcher.JamFetcher
-	storage         *storage.Manager
+	storage         storage.Backend  // Jam data, submissions and media manifests
+	files           *storage.Manager // Output directory: media, game files, crawl state, JSON Lines and reports
 	config          config.Config
-	gameCache       map[string]bool
+	gameCache       map[string]*cachedGame
 	gameCacheMutex  sync.RWMutex
+	plannedGames    map[string]bool // Games already counted by PlanJam in this run
+	rateLimitErr    error           // Rate limiting that outlasted the retries, which stops the run
+	rateLimitMutex  sync.Mutex
+	parseErrors     atomic.Int64    // Responses that could not be parsed
+}
+
+// cachedGame holds the game-level data of a game already processed in this run, which
+// is the same in every jam the game was entered in. Jam-specific data, such as the rate
+// page's comments and criteria answers, is still fetched for each jam.
+type cachedGame struct {
+	page      *fetcher.GamePage
+	mediaDone bool
+	filesDone bool
+	downloads []fetcher.Download // Downloads with their local paths and checksums
 }
 
-// NewProcessor creates a new Processor
-func NewProcessor(storage *storage.Manager, cfg config.Config) *Processor {
+// NewProcessor creates a new Processor storing jam data in backend and files in the
+// output directory of files
+func NewProcessor(backend storage.Backend, files *storage.Manager, jamFetcher *fetcher.JamFetcher, cfg config.Config) *Processor {
 	return &Processor{
-		fetcher:        fetcher.NewFetcher(cfg.UserAgent, cfg.RequestDelay),
-		storage:        storage,
+		fetcher:        jamFetcher,
+		storage:        backend,
+		files:          files,
 		config:         cfg,
-		gameCache:      make(map[string]bool),
+		gameCache:      make(map[string]*cachedGame),
 		gameCacheMutex: sync.RWMutex{},
 	}
 }
 
-// ProcessJam processes a single jam
-func (p *Processor) ProcessJam(jamID string) error {
-	log.Printf("Starting processing for jam: %s", jamID)
+// ProcessJam processes a single jam. When the context is cancelled no new games are
+// started, in-flight requests are aborted, the crawl state is flushed and the
+// context's error is returned. Output is stored under the jam's canonical identity,
+// its slug, while entries are fetched by its numeric ID. The returned report lists
+// the outcome of the jam and each of its games, also when an error is returned.
+func (p *Processor) ProcessJam(ctx context.Context, jam fetcher.JamRef) (*storage.JamReport, error) {
+	report := &storage.JamReport{
+		JamID:     jam.Key(),
+		NumericID: jam.ID,
+		StartedAt: time.Now(),
+	}
+
+	err := p.processJam(ctx, jam, report)
+
+	report.DurationSeconds = time.Since(report.StartedAt).Seconds()
+	switch {
+	case ctx.Err() != nil:
+		report.Outcome = storage.OutcomeInterrupted
+	case isStopErr(err):
+		report.Outcome = storage.OutcomeStopped
+	case err != nil:
+		report.Outcome = storage.OutcomeFailed
+	case report.Failed > 0:
+		report.Outcome = storage.OutcomePartial
+	default:
+		report.Outcome = storage.OutcomeDone
+	}
+	if err != nil {
+		report.Error = err.Error()
+	}
+
+	return report, err
+}
+
+// processJam does the work of ProcessJam, filling in the jam's report as it goes
+func (p *Processor) processJam(ctx context.Context, jam fetcher.JamRef, report *storage.JamReport) error {
+	jamID := jam.Key()
+	log.Printf("Starting processing for jam: %s", jam)
 	
 	// Create jam directory
 	jamDir := filepath.Join(p.config.OutputDir, "jams", jamID)
-	if err := p.storage.CreateDirectory(jamDir); err != nil {
+	if err := p.files.CreateDirectory(jamDir); err != nil {
 		log.Printf("Error: Failed to create jam directory for %s: %v", jamID, err)
 		return fmt.Errorf("failed to create jam directory: %w", err)
 	}
 	log.Printf("Jam directory created for: %s", jamID)
 
 	// Fetch jam metadata
-	metadata, err := p.fetcher.FetchJamMetadata(jamID)
+	metadata, err := p.fetcher.FetchJamMetadata(ctx, jam)
 	if err != nil {
 		log.Printf("Error: Failed to fetch jam metadata for %s: %v", jamID, err)
 		return fmt.Errorf("failed to fetch jam metadata: %w", err)
 	}
 	log.Printf("Fetched metadata for jam: %s, Title: %s", jamID, metadata.Title)
+	report.Title = metadata.Title
+	report.NumericID = metadata.InternalID
 
 	// Save jam metadata
 	if err := p.storage.SaveJamMetadata(jamID, metadata); err != nil {
@@ -63,172 +126,462 @@ func (p *Processor) ProcessJam(jamID string) error {
 	if metadata.CoverImageURL != "" && p.config.DownloadMedia {
 		log.Printf("Downloading cover image for jam: %s from URL: %s", jamID, metadata.CoverImageURL)
 		coverPath := filepath.Join(jamDir, "cover"+filepath.Ext(metadata.CoverImageURL))
-		if err := p.fetcher.DownloadFile(metadata.CoverImageURL, coverPath); err != nil {
+		if _, err := p.fetcher.DownloadFile(ctx, metadata.CoverImageURL, coverPath); err != nil {
+			p.noteFailure(err)
 			log.Printf("Warning: Failed to download jam cover image for %s: %v", jamID, err)
 		} else {
 			log.Printf("Downloaded cover image for jam: %s", jamID)
 		}
 	}
+	if err := p.stopErr(); err != nil {
+		return err
+	}
 
-	// Use InternalID to fetch jam entries
-	entriesResponse, err := p.fetcher.FetchJamEntries(metadata.InternalID)
+	// Fetch jam entries by numeric ID, unless resolving the jam already did
+	entriesResponse, err := p.fetcher.JamEntries(ctx, jam)
 	if err != nil {
-		log.Printf("Error: Failed to fetch jam entries from URL https://itch.io/jam/%s/entries.json for %s: %v", metadata.InternalID, jamID, err)
+		log.Printf("Error: Failed to fetch jam entries from URL %s/jam/%s/entries.json for %s: %v", p.fetcher.BaseURL(), jam.ID, jamID, err)
 		return fmt.Errorf("failed to fetch jam entries: %w", err)
 	}
 	log.Printf("Fetched %d entries for jam: %s", len(entriesResponse.JamGames), jamID)
 
-	// Process each game
-	var wg sync.WaitGroup
-	semaphore := make(chan struct{}, p.config.Workers)
+	// Narrow the entries down to the ones selected for scraping, in scraping order
+	entries, err := selectEntries(entriesResponse.JamGames, p.config)
+	if err != nil {
+		return fmt.Errorf("failed to select entries: %w", err)
+	}
+	report.Entries = len(entriesResponse.JamGames)
+	report.Selected = len(entries)
+	if len(entries) != len(entriesResponse.JamGames) {
+		log.Printf("Selected %d of %d entries for jam: %s", len(entries), len(entriesResponse.JamGames), jamID)
+	}
 
-	log.Printf("Beginning processing of games for jam: %s", jamID)
+	// Fetch final rankings; unfinished jams have no results page yet
+	resultsByGame := make(map[int]*fetcher.EntryResult) // Keyed by game ID, as results pages link to rate/{game ID}
+	if p.config.FetchResults {
+		results, err := p.fetcher.FetchJamResults(ctx, jamID)
+		if err != nil {
+			p.noteFailure(err)
+		}
+		if results == nil {
+			log.Printf("Warning: No results available for jam %s: %v", jamID, err)
+		} else {
+			// Results missing a criteria page are kept, as the overall ranking is complete
+			if err != nil {
+				log.Printf("Warning: Incomplete results for jam %s: %v", jamID, err)
+			}
+			for i := range results.Entries {
+				resultsByGame[results.Entries[i].GameID] = &results.Entries[i]
+			}
+			if err := p.storage.SaveJamResults(jamID, results); err != nil {
+				log.Printf("Warning: Failed to save results for jam %s: %v", jamID, err)
+			}
+			log.Printf("Fetched results for %d entries of jam: %s", len(results.Entries), jamID)
+		}
+	}
 
-	for _, jamGame := range entriesResponse.JamGames {
-		gameID := strconv.Itoa(jamGame.Game.ID)
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := p.stopErr(); err != nil {
+		return err
+	}
 
-		// Check if we've already processed this game
-		p.gameCacheMutex.RLock()
-		alreadyProcessed := p.gameCache[gameID]
-		p.gameCacheMutex.RUnlock()
+	// Start a fresh JSON Lines file for this run
+	if p.config.HasFormat(config.FormatJSONL) {
+		if err := p.files.ResetJSONL(p.files.SubmissionsJSONLPath(jamID)); err != nil {
+			return fmt.Errorf("failed to prepare jsonl output: %w", err)
+		}
+	}
 
-		if alreadyProcessed {
-			log.Printf("Skipping game %s as it has already been processed", gameID)
-			continue
+	run := &jamRun{
+		p:             p,
+		jamID:         jamID,
+		resultsByGame: resultsByGame,
+		games:         make([]*storage.GameReport, len(entries)),
+	}
+
+	// Every selected game is in the report, including those the run never reaches
+	for i, entry := range entries {
+		run.games[i] = &storage.GameReport{
+			GameID:  strconv.Itoa(entry.Game.ID),
+			Title:   entry.Game.Title,
+			Outcome: storage.OutcomeNotStarted,
 		}
+	}
+	report.Games = run.games
 
-		wg.Add(1)
-		semaphore <- struct{}{} // Acquire semaphore
+	// Collected submissions for the markdown report, kept in entry order
+	if p.config.HasFormat(config.FormatMarkdown) {
+		run.submissions = make([]*fetcher.GameSubmission, len(entries))
+	}
 
-		go func(jg fetcher.JamGame) {
-			defer wg.Done()
-			defer func() { <-semaphore }() // Release semaphore
+	// Load crawl state; without -resume every game starts from scratch
+	run.state = storage.NewCrawlState(jamID)
+	if p.config.Resume {
+		run.state, err = p.files.LoadCrawlState(jamID)
+		if err != nil {
+			return fmt.Errorf("failed to load crawl state: %w", err)
+		}
+		log.Printf("Resuming jam %s with %d games in crawl state", jamID, len(run.state.Games))
+	}
 
-			gameID := strconv.Itoa(jg.Game.ID)
-			log.Printf("Starting processing for game: %s", gameID)
+	// Process each game through the pipeline
+	log.Printf("Beginning processing of games for jam: %s", jamID)
+	parseErrors := p.parseErrors.Load()
+	run.run(ctx, entries)
+
+	report.Finished = int(run.finishedCount.Load())
+	report.Failed = int(run.failedCount.Load())
+	report.Skipped = int(run.skippedCount.Load())
+	report.Unchanged = int(run.unchangedCount.Load())
+	report.Gone = int(run.goneCount.Load())
+	report.ParseErrors = int(p.parseErrors.Load() - parseErrors)
+	if p.stopErr() != nil {
+		for _, game := range run.games {
+			if game.Outcome == storage.OutcomeInterrupted {
+				game.Outcome = storage.OutcomeStopped
+			}
+		}
+	}
 
-			// Mark this game as processed
-			p.gameCacheMutex.Lock()
-			p.gameCache[gameID] = true
-			p.gameCacheMutex.Unlock()
+	if err := p.files.SaveCrawlState(run.state); err != nil {
+		log.Printf("Warning: Failed to save crawl state for jam %s: %v", jamID, err)
+	}
 
+	log.Printf("Jam %s summary: %d of %d games finished, %d failed, %d skipped from an earlier run",
+		jamID, run.finishedCount.Load(), len(entries), run.failedCount.Load(), run.skippedCount.Load())
+	if p.config.Incremental {
+		log.Printf("Incremental scrape of jam %s: %d games unchanged and not refetched", jamID, run.unchangedCount.Load())
+	}
+	if report.Gone > 0 {
+		log.Printf("Jam %s: %d games were removed from itch.io and are kept with their entry data only", jamID, report.Gone)
+	}
+	if report.ParseErrors > 0 {
+		log.Printf("Warning: %d responses for jam %s could not be parsed; itch.io may have changed its page layout", report.ParseErrors, jamID)
+	}
 
-			// Create basic game submission from jam game
-			submission := &fetcher.GameSubmission{
-				ID:          gameID,
-				Title:       jg.Game.Title,
-				URL:         jg.Game.URL,
-				Platforms:   jg.Game.Platforms,
-				CreatedAt:   jg.CreatedAt,
-				Coolness:    jg.Coolness,
-				RatingCount: jg.RatingCount,
-				Cover: fetcher.CoverImage{
-					URL:   jg.Game.Cover,
-					Color: jg.Game.CoverColor,
-				},
+	if err := ctx.Err(); err != nil {
+		log.Printf("Interrupted while processing jam: %s", jamID)
+		return err
+	}
+	if err := p.stopErr(); err != nil {
+		log.Printf("Stopped processing jam %s: %v", jamID, err)
+		return err
+	}
+	log.Printf("Finished processing all games for jam: %s", jamID)
+
+	// Generate markdown report from the collected submissions
+	if run.submissions != nil {
+		games := make([]*fetcher.GameSubmission, 0, len(run.submissions))
+		for _, submission := range run.submissions {
+			if submission != nil {
+				games = append(games, submission)
 			}
-			log.Printf("Created submission for game: %s - %s", gameID, submission.Title)
+		}
+		if err := p.files.GenerateMarkdownReport(jamID, metadata, games); err != nil {
+			return fmt.Errorf("failed to generate markdown report: %w", err)
+		}
+		log.Printf("Generated markdown report for jam: %s", jamID)
+	}
 
-			// Add authors
-			submission.Authors = append(submission.Authors, jg.Game.User)
+	return nil
+}
 
-			// Add contributors if available
-			for _, contributor := range jg.Contributors {
-				submission.Authors = append(submission.Authors, fetcher.User{
-					Name: contributor.Name,
-					URL:  contributor.URL,
-				})
-			}
-			log.Printf("Added authors and contributors for game: %s", gameID)
+// applyDetails copies the scraped fields of a rate page result or a stored submission onto a submission
+func applyDetails(submission, details *fetcher.GameSubmission) {
+	submission.Description = details.Description
+	submission.Screenshots = details.Screenshots
+	submission.Downloads = details.Downloads
+	submission.Comments = details.Comments
+	submission.CriteriaResponses = details.CriteriaResponses
+	submission.Status = details.Status
+	submission.Genre = details.Genre
+	submission.Tags = details.Tags
+	submission.MadeWith = details.MadeWith
+	submission.AverageSession = details.AverageSession
+	submission.Inputs = details.Inputs
+	submission.Accessibility = details.Accessibility
+	submission.Languages = details.Languages
+	submission.License = details.License
+	submission.GameInfo = details.GameInfo
+	submission.FieldSources = mergeSources(nil, details.FieldSources)
+}
 
-			// Try to get more details
-			gameDetails, err := p.fetcher.FetchGameDetails(jamID, gameID)
-			if err != nil {
-				log.Printf("Warning: Failed to fetch details for game %s: %v", gameID, err)
-			} else {
-				// Update submission with additional details
-				submission.Description = gameDetails.Description
-				submission.Screenshots = gameDetails.Screenshots
-				submission.Downloads = gameDetails.Downloads
-				submission.Comments = gameDetails.Comments
-				submission.CriteriaResponses = gameDetails.CriteriaResponses
-				log.Printf("Fetched additional details for game: %s", gameID)
-			}
+// applyGamePage copies the fields scraped from a game's own page onto a submission
+func applyGamePage(submission *fetcher.GameSubmission, page *fetcher.GamePage) {
+	submission.Status = page.Status
+	submission.Genre = page.Genre
+	submission.Tags = page.Tags
+	submission.MadeWith = page.MadeWith
+	submission.AverageSession = page.AverageSession
+	submission.Inputs = page.Inputs
+	submission.Accessibility = page.Accessibility
+	submission.Languages = page.Languages
+	submission.License = page.License
+	submission.GameInfo = page.Info
+	submission.FieldSources = mergeSources(submission.FieldSources, page.FieldSources)
+}
 
-			// Save game submission
-			if err := p.storage.SaveGameSubmission(jamID, gameID, submission); err != nil {
-				log.Printf("Warning: Failed to save game submission %s: %v", gameID, err)
-				return
-			}
-			log.Printf("Saved game submission for game: %s", gameID)
+// mergeSources returns a copy of dst with the entries of src added, so submissions
+// never share a field source map with the page or record they were copied from
+func mergeSources(dst, src map[string]string) map[string]string {
+	if len(dst) == 0 && len(src) == 0 {
+		return nil
+	}
+	merged := make(map[string]string, len(dst)+len(src))
+	for field, source := range dst {
+		merged[field] = source
+	}
+	for field, source := range src {
+		merged[field] = source
+	}
+	return merged
+}
 
-			// Download media if configured
-			if p.config.DownloadMedia {
-				log.Printf("Downloading media for game: %s", gameID)
-				p.downloadGameMedia(jamID, gameID, submission)
-			}
+// markDeveloperComments flags comments written by one of the game's authors
+func markDeveloperComments(submission *fetcher.GameSubmission) {
+	authors := make(map[string]bool)
+	for _, author := range submission.Authors {
+		if author.URL != "" {
+			authors[strings.TrimRight(author.URL, "/")] = true
+		}
+	}
 
-			// Download game files if configured
-			if p.config.DownloadGames && len(submission.Downloads) > 0 {
-				log.Printf("Downloading game files for game: %s", gameID)
-				p.downloadGameFiles(jamID, gameID, submission)
-			}
+	for i := range submission.Comments {
+		comment := &submission.Comments[i]
+		if authors[strings.TrimRight(comment.AuthorURL, "/")] {
+			comment.IsDeveloper = true
+		}
+	}
+}
 
-			log.Printf("Finished processing game: %s - %s", gameID, submission.Title)
-		}(jamGame)
+// goneLabel prefixes the skip note of a game whose rate page no longer exists
+const goneLabel = "details"
+
+// entryUnchanged reports whether a stored submission is still current for a jam entry.
+// Submissions that had failed requests are never considered current, and neither are
+// removed games, so they are checked again and keep their gone status.
+func entryUnchanged(stored *fetcher.GameSubmission, jg fetcher.JamGame) bool {
+	return len(stored.FetchErrors) == 0 &&
+		!wasGone(stored) &&
+		stored.CreatedAt == jg.CreatedAt &&
+		stored.Coolness == jg.Coolness &&
+		stored.RatingCount == jg.RatingCount
+}
+
+// wasGone reports whether a stored submission was saved after its rate page was gone
+func wasGone(stored *fetcher.GameSubmission) bool {
+	for _, skipped := range stored.Skipped {
+		if strings.HasPrefix(skipped, goneLabel+": ") {
+			return true
+		}
 	}
+	return false
+}
 
-	wg.Wait()
-	log.Printf("Finished processing all games for jam: %s", jamID)
+// cachedGame returns a snapshot of the game-level data cached for a game, which is
+// empty for games not seen yet in this run
+func (p *Processor) cachedGame(gameID string) cachedGame {
+	p.gameCacheMutex.RLock()
+	defer p.gameCacheMutex.RUnlock()
+
+	if cached, ok := p.gameCache[gameID]; ok {
+		return *cached
+	}
+	return cachedGame{}
+}
+
+// updateCachedGame changes the cached game-level data of a game
+func (p *Processor) updateCachedGame(gameID string, update func(*cachedGame)) {
+	p.gameCacheMutex.Lock()
+	defer p.gameCacheMutex.Unlock()
+
+	cached, ok := p.gameCache[gameID]
+	if !ok {
+		cached = &cachedGame{}
+		p.gameCache[gameID] = cached
+	}
+	update(cached)
+}
+
+// updateState records a game's crawl status and periodically flushes the state to disk
+func (p *Processor) updateState(state *storage.CrawlState, gameID string, status storage.GameStatus, err error) {
+	if state.Update(gameID, status, err) < stateSaveInterval {
+		return
+	}
+	if err := p.files.SaveCrawlState(state); err != nil {
+		log.Printf("Warning: Failed to save crawl state for jam %s: %v", state.JamID, err)
+	}
+}
+
+// reuseFinishedGame adds a game finished in an earlier run to this run's jsonl and markdown outputs
+func (p *Processor) reuseFinishedGame(jamID, gameID string, index int, submissions []*fetcher.GameSubmission) {
+	if !p.config.HasFormat(config.FormatJSONL) && submissions == nil {
+		return
+	}
+
+	submission, err := p.storage.LoadGameSubmission(jamID, gameID)
+	if err != nil {
+		log.Printf("Warning: Finished game %s has no saved submission to include in this run's output: %v", gameID, err)
+		return
+	}
+
+	if p.config.HasFormat(config.FormatJSONL) {
+		if err := p.files.AppendToJSONL(p.files.SubmissionsJSONLPath(jamID), submission); err != nil {
+			log.Printf("Warning: Failed to append game %s to jsonl output: %v", gameID, err)
+		}
+	}
+	if submissions != nil {
+		submissions[index] = submission
+	}
+}
+
+// writeSubmission saves a game submission to the storage backend, and to the jsonl
+// output if it was requested
+func (p *Processor) writeSubmission(jamID, gameID string, submission *fetcher.GameSubmission) error {
+	if err := p.storage.SaveGameSubmission(jamID, gameID, submission); err != nil {
+		return err
+	}
+
+	if p.config.HasFormat(config.FormatJSONL) {
+		if err := p.files.AppendToJSONL(p.files.SubmissionsJSONLPath(jamID), submission); err != nil {
+			return err
+		}
+	}
 
 	return nil
 }
 
-// downloadGameMedia downloads media files for a game
-func (p *Processor) downloadGameMedia(jamID, gameID string, game *fetcher.GameSubmission) {
-	gameMediaDir := filepath.Join(p.config.OutputDir, "jams", jamID, "submissions", gameID, "media")
+// downloadGameMedia downloads media files for a game and records their checksums in the media manifest
+func (p *Processor) downloadGameMedia(ctx context.Context, gameID string, game *fetcher.GameSubmission) {
+	gameMediaDir := p.files.MediaDir(gameID)
 	
 	// Create media directory
-	if err := p.storage.CreateDirectory(gameMediaDir); err != nil {
+	if err := p.files.CreateDirectory(gameMediaDir); err != nil {
 		log.Printf("Warning: Failed to create media directory for game %s: %v", gameID, err)
 		return
 	}
+
+	// Files downloaded by an earlier run stay listed when this run fails to download them again
+	manifest, err := p.storage.LoadMediaManifest(gameID)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			log.Printf("Warning: Failed to load media manifest for game %s, starting a new one: %v", gameID, err)
+		}
+		manifest = &storage.MediaManifest{}
+	}
+
+	download := func(mediaURL, name, label string) {
+		result, err := p.fetcher.DownloadFile(ctx, mediaURL, filepath.Join(gameMediaDir, name))
+		if err != nil {
+			p.downloadFailed(game, gameID, label, err)
+			return
+		}
+		manifest.Put(storage.MediaFile{
+			Name:         name,
+			URL:          result.URL,
+			Size:         result.Size,
+			SHA256:       result.SHA256,
+			DownloadedAt: time.Now(),
+		})
+	}
 	
 	// Download cover image
 	if game.Cover.URL != "" {
-		coverPath := filepath.Join(gameMediaDir, "cover"+filepath.Ext(game.Cover.URL))
-		if err := p.fetcher.DownloadFile(game.Cover.URL, coverPath); err != nil {
-			log.Printf("Warning: Failed to download cover for game %s: %v", gameID, err)
-		}
+		download(game.Cover.URL, "cover"+filepath.Ext(game.Cover.URL), "cover")
 	}
 	
 	// Download screenshots
 	for i, screenshot := range game.Screenshots {
-		screenshotPath := filepath.Join(gameMediaDir, fmt.Sprintf("screenshot%d%s", i+1, filepath.Ext(screenshot)))
-		if err := p.fetcher.DownloadFile(screenshot, screenshotPath); err != nil {
-			log.Printf("Warning: Failed to download screenshot %d for game %s: %v", i+1, gameID, err)
+		if ctx.Err() != nil {
+			return
 		}
+		download(screenshot, fmt.Sprintf("screenshot%d%s", i+1, filepath.Ext(screenshot)), fmt.Sprintf("screenshot %d", i+1))
+	}
+
+	if err := p.storage.SaveMediaManifest(gameID, manifest); err != nil {
+		log.Printf("Warning: Failed to save media manifest for game %s: %v", gameID, err)
+		game.FetchErrors = append(game.FetchErrors, fmt.Sprintf("media manifest: %v", err))
 	}
 }
 
-// downloadGameFiles downloads game files
-func (p *Processor) downloadGameFiles(jamID, gameID string, game *fetcher.GameSubmission) {
-	gameFilesDir := filepath.Join(p.config.OutputDir, "jams", jamID, "submissions", gameID, "files")
+// downloadGameFiles downloads the uploads listed on a game's page into its files directory,
+// recording each file's path, size and checksum on the submission
+func (p *Processor) downloadGameFiles(ctx context.Context, gameID string, game *fetcher.GameSubmission, page *fetcher.GamePage) {
+	gameFilesDir := p.files.FilesDir(gameID)
 	
 	// Create game files directory
-	if err := p.storage.CreateDirectory(gameFilesDir); err != nil {
+	if err := p.files.CreateDirectory(gameFilesDir); err != nil {
 		log.Printf("Warning: Failed to create game files directory for game %s: %v", gameID, err)
 		return
 	}
-	
-	for _, download := range game.Downloads {
-		// Extract download URL from game page
-		// Note: This would require additional scraping logic as download URLs
-		// are typically not directly accessible without login
-		log.Printf("Note: Game file downloading requires additional authentication and is not fully implemented: %s", download.Filename)
+
+	// The game page carries the upload IDs and the CSRF token for the download endpoint
+	if page == nil {
+		var err error
+		page, err = p.fetcher.FetchGamePage(ctx, game.URL)
+		if err != nil {
+			p.pageFailed(game, gameID, "uploads", err)
+			return
+		}
 	}
-}
+	mergeUploads(game, page.Uploads)
+	
+	for i := range game.Downloads {
+		if ctx.Err() != nil {
+			return
+		}
+
+		download := &game.Downloads[i]
+		if download.UploadID == 0 {
+			log.Printf("Warning: No upload ID for file %s of game %s", download.Filename, gameID)
+			game.FetchErrors = append(game.FetchErrors, fmt.Sprintf("file %s: no upload ID", download.Filename))
+			continue
+		}
 
+		// Skip files listed as larger than the budget allows without requesting them
+		if size, ok := parseSize(download.Size); ok && p.config.MaxFileBytes > 0 && size > p.config.MaxFileBytes {
+			log.Printf("Skipping file %s of game %s: listed as %s, over the maximum file size", download.Filename, gameID, download.Size)
+			game.Skipped = append(game.Skipped, fmt.Sprintf("file %s: listed as %s, over the maximum file size of %d bytes", download.Filename, download.Size, p.config.MaxFileBytes))
+			continue
+		}
 
+		result, err := p.fetcher.DownloadUpload(ctx, *download, page.CSRFToken, gameFilesDir)
+		if err != nil {
+			p.downloadFailed(game, gameID, "file "+download.Filename, err)
+			continue
+		}
 
+		download.Path = p.files.RelPath(result.Path)
+		download.Bytes = result.Size
+		download.SHA256 = result.SHA256
+		log.Printf("Downloaded file %s (%d bytes) for game %s", download.Filename, result.Size, gameID)
+	}
+}
 
+// mergeUploads adds upload IDs from the game page, or files downloaded for another jam,
+// to the submission's downloads, appending uploads the rate page did not list
+func mergeUploads(game *fetcher.GameSubmission, uploads []fetcher.Download) {
+	for _, upload := range uploads {
+		merged := false
+		for i := range game.Downloads {
+			download := &game.Downloads[i]
+			if (download.UploadID != 0 && download.UploadID == upload.UploadID) ||
+				(download.UploadID == 0 && download.Filename == upload.Filename) {
+				download.UploadID = upload.UploadID
+				download.URL = upload.URL
+				if upload.Path != "" {
+					download.Path = upload.Path
+					download.Bytes = upload.Bytes
+					download.SHA256 = upload.SHA256
+				}
+				merged = true
+				break
+			}
+		}
+		if !merged {
+			game.Downloads = append(game.Downloads, upload)
+		}
+	}
+}
@@ -0,0 +1,313 @@
+package processor
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"Itchalyser/config"
+	"Itchalyser/fetcher"
+	"Itchalyser/storage"
+)
+
+// newFixtureServer serves a finished jam whose entry IDs differ from its game IDs, as
+// on itch.io: entries.json lists entries 11, 12 and 13 of games 101, 102 and 103, and
+// the results page links to the rate pages of the two ranked games
+func newFixtureServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(newFixtureMux())
+	t.Cleanup(server.Close)
+	return server
+}
+
+// newFixtureMux returns the handlers of the fixture jam, for tests that add their own
+func newFixtureMux() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/jam/fixture-jam", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<html><head><script>I.ViewJam("#jam", {"jam":{"id":4242,"title":"Fixture Jam"}})</script></head><body></body></html>`)
+	})
+	mux.HandleFunc("/jam/4242/entries.json", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"jam_games":[
+{"id":11,"url":"/jam/fixture-jam/rate/101","rating_count":12,"game":{"id":101,"title":"Alpha","user":{"name":"ann"}}},
+{"id":12,"url":"/jam/fixture-jam/rate/102","rating_count":20,"game":{"id":102,"title":"Beta","user":{"name":"bob"}}},
+{"id":13,"url":"/jam/fixture-jam/rate/103","rating_count":2,"game":{"id":103,"title":"Gamma","user":{"name":"cat"}}}
+]}`)
+	})
+	mux.HandleFunc("/jam/fixture-jam/results", func(w http.ResponseWriter, r *http.Request) {
+		row := func(gameID int, title string, rank int) string {
+			return fmt.Sprintf(`<div class="game_rank"><div class="game_summary"><h2><a href="/jam/fixture-jam/rate/%d">%s</a></h2><h3>Ranked #%d with 10 ratings</h3></div>
+<div class="ranking_results_table"><table><tr><th>Criteria</th><th>Rank</th><th>Score*</th><th>Raw Score</th></tr>
+<tr><td>Overall</td><td>#%d</td><td>4.000</td><td>4.100</td></tr></table></div></div>`, gameID, title, rank, rank)
+		}
+		fmt.Fprint(w, `<html><body>`+row(102, "Beta", 1)+row(101, "Alpha", 2)+`</body></html>`)
+	})
+	mux.HandleFunc("/jam/fixture-jam/rate/", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<html><body><div class="formatted_description">A game</div></body></html>`)
+	})
+	return mux
+}
+
+// fixtureConfig returns the configuration for scraping the fixture jam into a temporary directory
+func fixtureConfig(t *testing.T, baseURL string) config.Config {
+	return config.Config{
+		OutputFormat:      config.FormatJSON,
+		OutputDir:         t.TempDir(),
+		Backend:           config.BackendFiles,
+		Workers:           1,
+		RequestsPerSecond: 1000,
+		Burst:             100,
+		MaxAttempts:       1,
+		BaseURL:           baseURL,
+		FetchResults:      true,
+	}
+}
+
+// scrapeFixture runs the processor once over the fixture jam
+func scrapeFixture(t *testing.T, cfg config.Config) (*storage.Manager, string, *storage.JamReport) {
+	t.Helper()
+	files := storage.NewManager(cfg.OutputDir)
+	jamFetcher := fetcher.NewFetcher(cfg)
+	p := NewProcessor(files, files, jamFetcher, cfg)
+
+	jam, err := jamFetcher.ResolveJam(context.Background(), "fixture-jam")
+	if err != nil {
+		t.Fatal(err)
+	}
+	report, err := p.ProcessJam(context.Background(), jam)
+	if err != nil {
+		t.Fatalf("ProcessJam: %v", err)
+	}
+	return files, jam.Key(), report
+}
+
+func TestProcessJamMatchesResultsByGameID(t *testing.T) {
+	server := newFixtureServer(t)
+	files, jamID, report := scrapeFixture(t, fixtureConfig(t, server.URL))
+	if report.Outcome != storage.OutcomeDone {
+		t.Errorf("outcome = %s, want %s", report.Outcome, storage.OutcomeDone)
+	}
+
+	tests := []struct {
+		gameID   string
+		wantRank int
+	}{
+		{"101", 2},
+		{"102", 1},
+	}
+	for _, tt := range tests {
+		submission, err := files.LoadGameSubmission(jamID, tt.gameID)
+		if err != nil {
+			t.Fatalf("game %s: %v", tt.gameID, err)
+		}
+		if submission.Results == nil {
+			t.Errorf("game %s has no results", tt.gameID)
+			continue
+		}
+		if got := fmt.Sprint(submission.Results.GameID); got != tt.gameID || submission.Results.Rank != tt.wantRank {
+			t.Errorf("game %s results = game %d ranked #%d, want game %s ranked #%d",
+				tt.gameID, submission.Results.GameID, submission.Results.Rank, tt.gameID, tt.wantRank)
+		}
+	}
+}
+
+func TestIncrementalKeepsGoneGames(t *testing.T) {
+	mux := newFixtureMux()
+	mux.HandleFunc("/jam/fixture-jam/rate/102", http.NotFound)
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	cfg := fixtureConfig(t, server.URL)
+	cfg.DownloadMedia = true
+	scrapeFixture(t, cfg)
+
+	// The second run finds game 102's stored submission with unchanged stats
+	cfg.Incremental = true
+	files, jamID, report := scrapeFixture(t, cfg)
+	if report.Gone != 1 {
+		t.Errorf("gone = %d, want 1", report.Gone)
+	}
+
+	state, err := files.LoadCrawlState(jamID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		gameID       string
+		wantStatus   storage.GameStatus
+		wantMediaDir bool
+	}{
+		{"101", storage.StatusMediaDone, true},
+		{"102", storage.StatusGone, false},
+	}
+	for _, tt := range tests {
+		if got := state.Status(tt.gameID); got != tt.wantStatus {
+			t.Errorf("game %s status = %s, want %s", tt.gameID, got, tt.wantStatus)
+		}
+		submission, err := files.LoadGameSubmission(jamID, tt.gameID)
+		if err != nil {
+			t.Fatalf("game %s: %v", tt.gameID, err)
+		}
+		if got := submission.MediaDir != ""; got != tt.wantMediaDir {
+			t.Errorf("game %s media dir = %q, want set %v", tt.gameID, submission.MediaDir, tt.wantMediaDir)
+		}
+	}
+}
+
+// requestCounter counts the requests to each path of a test server
+type requestCounter struct {
+	mu   sync.Mutex
+	hits map[string]int
+}
+
+func (c *requestCounter) wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c.mu.Lock()
+		c.hits[r.URL.Path]++
+		c.mu.Unlock()
+		next.ServeHTTP(w, r)
+	})
+}
+
+// reset returns the counts so far and starts counting again
+func (c *requestCounter) reset() map[string]int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	hits := c.hits
+	c.hits = make(map[string]int)
+	return hits
+}
+
+func TestResume(t *testing.T) {
+	// Game 102's rate page fails during the first run only
+	var failing atomic.Bool
+	failing.Store(true)
+	mux := newFixtureMux()
+	mux.HandleFunc("/jam/fixture-jam/rate/102", func(w http.ResponseWriter, r *http.Request) {
+		if failing.Load() {
+			http.Error(w, "unavailable", http.StatusInternalServerError)
+			return
+		}
+		fmt.Fprint(w, `<html><body><div class="formatted_description">Beta, fetched again</div></body></html>`)
+	})
+	counter := &requestCounter{hits: make(map[string]int)}
+	server := httptest.NewServer(counter.wrap(mux))
+	t.Cleanup(server.Close)
+
+	cfg := fixtureConfig(t, server.URL)
+	files, jamID, _ := scrapeFixture(t, cfg)
+
+	// Leave game 103 as if the first run was interrupted after saving its details
+	state, err := files.LoadCrawlState(jamID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := state.Status("102"); got != storage.StatusFailed {
+		t.Fatalf("game 102 status after the first run = %s, want %s", got, storage.StatusFailed)
+	}
+	state.Update("103", storage.StatusDetailsFetched, nil)
+	if err := files.SaveCrawlState(state); err != nil {
+		t.Fatal(err)
+	}
+	saved, err := files.LoadGameSubmission(jamID, "103")
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved.Description = "Saved details"
+	if err := files.SaveGameSubmission(jamID, "103", saved); err != nil {
+		t.Fatal(err)
+	}
+
+	failing.Store(false)
+	counter.reset()
+	cfg.Resume = true
+	files, jamID, report := scrapeFixture(t, cfg)
+	hits := counter.reset()
+
+	if report.Skipped != 1 || report.Failed != 0 {
+		t.Errorf("skipped, failed = %d, %d, want 1, 0", report.Skipped, report.Failed)
+	}
+	if state, err = files.LoadCrawlState(jamID); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		gameID          string
+		wantRequests    int
+		wantDescription string
+	}{
+		{"101", 0, "A game"},              // media_done: skipped
+		{"102", 1, "Beta, fetched again"}, // failed: retried
+		{"103", 0, "Saved details"},       // details_fetched: saved details reused
+	}
+	for _, tt := range tests {
+		if got := hits["/jam/fixture-jam/rate/"+tt.gameID]; got != tt.wantRequests {
+			t.Errorf("game %s rate page requests = %d, want %d", tt.gameID, got, tt.wantRequests)
+		}
+		if got := state.Status(tt.gameID); got != storage.StatusMediaDone {
+			t.Errorf("game %s status = %s, want %s", tt.gameID, got, storage.StatusMediaDone)
+		}
+		submission, err := files.LoadGameSubmission(jamID, tt.gameID)
+		if err != nil {
+			t.Fatalf("game %s: %v", tt.gameID, err)
+		}
+		if submission.Description != tt.wantDescription {
+			t.Errorf("game %s description = %q, want %q", tt.gameID, submission.Description, tt.wantDescription)
+		}
+	}
+}
+
+func TestMediaManifestKeepsEarlierDownloads(t *testing.T) {
+	// Game 101 has two screenshots; the second fails to download during the second run
+	var serverURL string
+	var failing atomic.Bool
+	mux := newFixtureMux()
+	mux.HandleFunc("/jam/fixture-jam/rate/101", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `<html><body><div class="formatted_description">A game</div>
+<a data-screenshot_id="1" data-screenshot_src="%[1]s/img/shot1.png"></a>
+<a data-screenshot_id="2" data-screenshot_src="%[1]s/img/shot2.png"></a></body></html>`, serverURL)
+	})
+	mux.HandleFunc("/img/", func(w http.ResponseWriter, r *http.Request) {
+		if failing.Load() && r.URL.Path == "/img/shot2.png" {
+			http.Error(w, "unavailable", http.StatusInternalServerError)
+			return
+		}
+		fmt.Fprint(w, "image "+r.URL.Path)
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	serverURL = server.URL
+
+	cfg := fixtureConfig(t, server.URL)
+	cfg.DownloadMedia = true
+	files, _, _ := scrapeFixture(t, cfg)
+	first, err := files.LoadMediaManifest("101")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	failing.Store(true)
+	scrapeFixture(t, cfg)
+	second, err := files.LoadMediaManifest("101")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	for _, file := range second.Files {
+		names = append(names, file.Name)
+	}
+	if want := []string{"screenshot1.png", "screenshot2.png"}; fmt.Sprint(names) != fmt.Sprint(want) {
+		t.Fatalf("manifest files = %v, want %v", names, want)
+	}
+	if !second.Files[0].DownloadedAt.After(first.Files[0].DownloadedAt) {
+		t.Errorf("screenshot1 downloaded at %s, want it downloaded again after %s", second.Files[0].DownloadedAt, first.Files[0].DownloadedAt)
+	}
+	if second.Files[1] != first.Files[1] {
+		t.Errorf("screenshot2 = %+v, want the first run's entry %+v", second.Files[1], first.Files[1])
+	}
+}
@@ -0,0 +1,168 @@
+package processor
+
+import (
+	"cmp"
+	"fmt"
+	"math/rand/v2"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"Itchalyser/config"
+	"Itchalyser/fetcher"
+)
+
+// selectEntries picks the entries of a jam to scrape. Entries are filtered first, then
+// sampled, then sorted, and finally cut to the limit, so the top N of a sort or a
+// reproducible random sample can be taken from any subset of a jam.
+func selectEntries(entries []fetcher.JamGame, cfg config.Config) ([]fetcher.JamGame, error) {
+	filter, err := newEntryFilter(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	selected := make([]fetcher.JamGame, 0, len(entries))
+	for _, entry := range entries {
+		if filter.matches(entry) {
+			selected = append(selected, entry)
+		}
+	}
+
+	// Sample without changing the entry order, so an unsorted sample stays in jam order
+	if cfg.SampleSize > 0 && cfg.SampleSize < len(selected) {
+		rng := rand.New(rand.NewPCG(uint64(cfg.SampleSeed), 0))
+		picked := rng.Perm(len(selected))[:cfg.SampleSize]
+		slices.Sort(picked)
+
+		sample := make([]fetcher.JamGame, 0, len(picked))
+		for _, i := range picked {
+			sample = append(sample, selected[i])
+		}
+		selected = sample
+	}
+
+	key, descending, err := cfg.SortOrder()
+	if err != nil {
+		return nil, err
+	}
+	if key != "" {
+		slices.SortStableFunc(selected, func(a, b fetcher.JamGame) int {
+			c := compareEntries(a, b, key)
+			if descending {
+				return -c
+			}
+			return c
+		})
+	}
+
+	if cfg.Limit > 0 && cfg.Limit < len(selected) {
+		selected = selected[:cfg.Limit]
+	}
+
+	return selected, nil
+}
+
+// entryFilter holds the parsed entry filters of a configuration
+type entryFilter struct {
+	platforms     map[string]bool
+	minRatings    int
+	minCoolness   int
+	createdAfter  time.Time
+	createdBefore time.Time
+	title         *regexp.Regexp
+	author        *regexp.Regexp
+}
+
+// newEntryFilter parses the entry filters of a configuration
+func newEntryFilter(cfg config.Config) (*entryFilter, error) {
+	filter := &entryFilter{
+		minRatings:  cfg.MinRatings,
+		minCoolness: cfg.MinCoolness,
+	}
+
+	for _, platform := range cfg.Platforms {
+		if platform = strings.ToLower(strings.TrimSpace(platform)); platform != "" {
+			if filter.platforms == nil {
+				filter.platforms = make(map[string]bool)
+			}
+			filter.platforms[platform] = true
+		}
+	}
+
+	var err error
+	if filter.createdAfter, filter.createdBefore, err = cfg.CreatedWindow(); err != nil {
+		return nil, err
+	}
+
+	if cfg.TitlePattern != "" {
+		if filter.title, err = regexp.Compile(cfg.TitlePattern); err != nil {
+			return nil, fmt.Errorf("invalid title pattern: %w", err)
+		}
+	}
+	if cfg.AuthorPattern != "" {
+		if filter.author, err = regexp.Compile(cfg.AuthorPattern); err != nil {
+			return nil, fmt.Errorf("invalid author pattern: %w", err)
+		}
+	}
+
+	return filter, nil
+}
+
+// matches reports whether an entry passes every filter
+func (f *entryFilter) matches(entry fetcher.JamGame) bool {
+	if entry.RatingCount < f.minRatings || entry.Coolness < f.minCoolness {
+		return false
+	}
+
+	if f.platforms != nil && !slices.ContainsFunc(entry.Game.Platforms, func(platform string) bool {
+		return f.platforms[strings.ToLower(platform)]
+	}) {
+		return false
+	}
+
+	if !f.createdAfter.IsZero() || !f.createdBefore.IsZero() {
+		created, err := time.Parse(config.EntryTimeLayout, entry.CreatedAt)
+		if err != nil {
+			return false
+		}
+		if !f.createdAfter.IsZero() && created.Before(f.createdAfter) {
+			return false
+		}
+		if !f.createdBefore.IsZero() && !created.Before(f.createdBefore) {
+			return false
+		}
+	}
+
+	if f.title != nil && !f.title.MatchString(entry.Game.Title) {
+		return false
+	}
+
+	if f.author != nil {
+		matched := f.author.MatchString(entry.Game.User.Name)
+		for _, contributor := range entry.Contributors {
+			matched = matched || f.author.MatchString(contributor.Name)
+		}
+		if !matched {
+			return false
+		}
+	}
+
+	return true
+}
+
+// compareEntries orders two entries by a sort key, in ascending order
+func compareEntries(a, b fetcher.JamGame, key string) int {
+	switch key {
+	case config.SortCoolness:
+		return cmp.Compare(a.Coolness, b.Coolness)
+	case config.SortRatings:
+		return cmp.Compare(a.RatingCount, b.RatingCount)
+	case config.SortCreated:
+		// created_at sorts chronologically as text
+		return strings.Compare(a.CreatedAt, b.CreatedAt)
+	case config.SortTitle:
+		return strings.Compare(strings.ToLower(a.Game.Title), strings.ToLower(b.Game.Title))
+	}
+	return 0
+}
@@ -0,0 +1,114 @@
+package processor
+
+import (
+	"slices"
+	"testing"
+
+	"Itchalyser/config"
+	"Itchalyser/fetcher"
+)
+
+// testEntries returns a small jam's entries, in jam order
+func testEntries() []fetcher.JamGame {
+	entry := func(id int, title, author, created string, coolness, ratings int, platforms ...string) fetcher.JamGame {
+		return fetcher.JamGame{
+			ID:          id * 10,
+			CreatedAt:   created,
+			Coolness:    coolness,
+			RatingCount: ratings,
+			Game: fetcher.Game{
+				ID:        id,
+				Title:     title,
+				Platforms: platforms,
+				User:      fetcher.User{Name: author},
+			},
+		}
+	}
+
+	entries := []fetcher.JamGame{
+		entry(1, "Alpha", "ann", "2024-01-01 10:00:00", 5, 3, "web"),
+		entry(2, "beta", "bob", "2024-01-02 10:00:00", 9, 12, "windows"),
+		entry(3, "Gamma", "cat", "2024-01-03 10:00:00", 1, 0, "web", "linux"),
+		entry(4, "Delta", "dan", "2024-01-04 10:00:00", 7, 20, "osx"),
+	}
+	entries[3].Contributors = []fetcher.Contributor{{Name: "ann"}}
+	return entries
+}
+
+func TestSelectEntries(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     config.Config
+		want    []int // Game IDs in scraping order
+		wantErr bool
+	}{
+		{"everything in jam order", config.Config{}, []int{1, 2, 3, 4}, false},
+		{"platform", config.Config{Platforms: []string{"Web"}}, []int{1, 3}, false},
+		{"several platforms", config.Config{Platforms: []string{"osx", "linux"}}, []int{3, 4}, false},
+		{"min ratings", config.Config{MinRatings: 10}, []int{2, 4}, false},
+		{"min coolness", config.Config{MinCoolness: 6}, []int{2, 4}, false},
+		{"created after", config.Config{CreatedAfter: "2024-01-02"}, []int{2, 3, 4}, false},
+		{"created before", config.Config{CreatedBefore: "2024-01-03 10:00:00"}, []int{1, 2}, false},
+		{"title pattern", config.Config{TitlePattern: "(?i)^[ab]"}, []int{1, 2}, false},
+		{"author or contributor", config.Config{AuthorPattern: "^ann$"}, []int{1, 4}, false},
+		{"sort by coolness", config.Config{SortBy: "coolness"}, []int{2, 4, 1, 3}, false},
+		{"sort by ratings ascending", config.Config{SortBy: "ratings:asc"}, []int{3, 1, 2, 4}, false},
+		{"sort by title", config.Config{SortBy: "title"}, []int{1, 2, 4, 3}, false},
+		{"newest first", config.Config{SortBy: "created:desc"}, []int{4, 3, 2, 1}, false},
+		{"top N", config.Config{SortBy: "ratings", Limit: 2}, []int{4, 2}, false},
+		{"filter then limit", config.Config{Platforms: []string{"web"}, Limit: 1}, []int{1}, false},
+		{"limit over the entry count", config.Config{Limit: 10}, []int{1, 2, 3, 4}, false},
+		{"sample larger than the jam", config.Config{SampleSize: 10}, []int{1, 2, 3, 4}, false},
+		{"unknown sort key", config.Config{SortBy: "popularity"}, nil, true},
+		{"invalid date", config.Config{CreatedAfter: "yesterday"}, nil, true},
+		{"invalid pattern", config.Config{TitlePattern: "("}, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			selected, err := selectEntries(testEntries(), tt.cfg)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("selectEntries() = %v, want an error", gameIDs(selected))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := gameIDs(selected); !slices.Equal(got, tt.want) {
+				t.Errorf("selectEntries() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSelectEntriesSample(t *testing.T) {
+	cfg := config.Config{SampleSize: 2, SampleSeed: 42}
+
+	first, err := selectEntries(testEntries(), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(first) != 2 {
+		t.Fatalf("sampled %d entries, want 2", len(first))
+	}
+
+	// The same seed gives the same sample, still in jam order
+	second, _ := selectEntries(testEntries(), cfg)
+	if !slices.Equal(gameIDs(first), gameIDs(second)) {
+		t.Errorf("samples with the same seed differ: %v and %v", gameIDs(first), gameIDs(second))
+	}
+	if !slices.IsSorted(gameIDs(first)) {
+		t.Errorf("unsorted sample %v is not in jam order", gameIDs(first))
+	}
+}
+
+// gameIDs returns the game IDs of entries
+func gameIDs(entries []fetcher.JamGame) []int {
+	ids := make([]int, 0, len(entries))
+	for _, entry := range entries {
+		ids = append(ids, entry.Game.ID)
+	}
+	return ids
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"strings"
+
+	"Itchalyser/config"
+)
+
+// runReport is the report command. It generates the reports of scraped jams from the
+// output directory without touching the network.
+func runReport(args []string) int {
+	fs := flag.NewFlagSet("report", flag.ExitOnError)
+	archive := addArchiveFlags(fs)
+	formats := fs.String("format", "markdown,html", "Report formats, comma-separated (markdown, html)")
+	fs.Parse(args)
+
+	var markdown, html bool
+	for _, format := range strings.Split(*formats, ",") {
+		switch strings.TrimSpace(format) {
+		case config.FormatMarkdown:
+			markdown = true
+		case "html":
+			html = true
+		default:
+			log.Fatalf("Unknown report format: %s (supported: markdown, html)", format)
+		}
+	}
+
+	store, backend, jams := archive.open()
+	defer backend.Close()
+
+	// Reports need every submission of a jam at once, so jams are loaded one at a time
+	failed := 0
+	for _, opened := range jams {
+		jam, err := opened.Load()
+		if err != nil {
+			log.Printf("Error loading jam %s: %v", opened.ID, err)
+			failed++
+			continue
+		}
+
+		if markdown {
+			if err := store.GenerateMarkdownReport(jam.ID, jam.Metadata, jam.Submissions); err != nil {
+				log.Printf("Error generating markdown report for jam %s: %v", jam.ID, err)
+				failed++
+				continue
+			}
+		}
+		if html {
+			if err := store.GenerateHTMLReport(jam); err != nil {
+				log.Printf("Error generating HTML report for jam %s: %v", jam.ID, err)
+				failed++
+				continue
+			}
+		}
+		fmt.Printf("Generated reports for jam %s (%d submissions)\n", jam.ID, len(jam.Submissions))
+	}
+
+	switch {
+	case failed == len(jams):
+		return exitFailed
+	case failed > 0:
+		return exitPartial
+	}
+	return exitOK
+}
@@ -0,0 +1,389 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"Itchalyser/config"
+	"Itchalyser/fetcher"
+	"Itchalyser/processor"
+	"Itchalyser/storage"
+)
+
+// runScrape is the scrape command. It scrapes jams from itch.io into the output
+// directory and returns the exit code of the run.
+func runScrape(args []string) int {
+	fs := flag.NewFlagSet("scrape", flag.ExitOnError)
+
+	// Default user agent
+	DefaultUserAgent := "Itchalyser/1.0 (https://github.com/Abstractmelon/Itchalyser)"
+
+	// Parse command line flags
+	jamURLs := fs.String("jam", "", "Comma-separated list of jams: slugs, numeric IDs, or jam, entry or results URLs")
+	outputFormat := fs.String("output", "json", "Output formats, comma-separated: json (the storage backend only), jsonl, markdown. Submissions are always saved to the storage backend")
+	outputDir := fs.String("dir", "../data", "Directory to store output")
+	storageBackend := fs.String("backend", config.BackendFiles, "Where to store jam data and submissions: "+strings.Join(config.Backends, ", "))
+	workers := fs.Int("workers", 2, "Number of workers scraping rate pages and game pages")
+	writeWorkers := fs.Int("write-workers", 2, "Number of workers saving scraped details to disk")
+	mediaWorkers := fs.Int("media-workers", 4, "Number of workers downloading media and game files")
+	queueSize := fs.Int("queue", 8, "Number of games that can wait between two pipeline stages")
+	userAgent := fs.String("user-agent", DefaultUserAgent, "User agent string for HTTP requests")
+	requestDelay := fs.Int("delay", 1500, "Delay between requests in milliseconds (default: 1500)")
+	requestsPerSecond := fs.Float64("rps", 0, "Requests per second to itch.io shared by all workers (default: derived from -delay)")
+	burst := fs.Int("burst", 1, "Number of itch.io requests allowed back to back")
+	cdnRequestsPerSecond := fs.Float64("cdn-rps", 0, "Requests per second for media downloads from CDN hosts (default: same as -rps)")
+	cdnBurst := fs.Int("cdn-burst", 1, "Number of CDN downloads allowed back to back")
+	maxAttempts := fs.Int("retries", 4, "Maximum attempts per request before giving up")
+	retryDelay := fs.Int("retry-delay", 1000, "Delay before the first retry in milliseconds, doubled on each retry")
+	baseURL := fs.String("base-url", config.DefaultBaseURL, "Base URL for itch.io requests (e.g. a local mock server)")
+	apiKey := fs.String("api-key", "", "itch.io API key for restricted downloads (default: $ITCHIO_API_KEY)")
+	apiURL := fs.String("api-url", config.DefaultAPIURL, "Base URL of the itch.io API")
+	cookieFile := fs.String("cookies", "", "Cookie file (Netscape cookies.txt) from a logged-in itch.io session")
+	downloadMedia := fs.Bool("media", true, "Download media files")
+	downloadGames := fs.Bool("games", false, "Download game files")
+	fetchGamePage := fs.Bool("game-page", false, "Scrape each game's own page for tags, genre, engine and other details, at one more request per game")
+	fetchResults := fs.Bool("results", true, "Fetch results and rankings of finished jams")
+	incremental := fs.Bool("incremental", false, "Only refetch details of entries that are new or changed since the last scrape")
+	resume := fs.Bool("resume", false, "Resume an interrupted run, skipping finished games and retrying failed ones")
+	maxRequests := fs.Int64("max-requests", 0, "Stop after this many HTTP requests, retries included (0 for no limit)")
+	maxFileSize := fs.String("max-file-size", "", "Skip media and game files larger than this, e.g. 500MB (default: no limit)")
+	maxTotalSize := fs.String("max-total-size", "", "Stop after downloading this much in total, e.g. 20GB (default: no limit)")
+	minFreeDisk := fs.String("min-free-disk", "", "Stop downloading when less than this is free on the output disk, e.g. 5GB (default: no limit)")
+	dryRun := fs.Bool("dry-run", false, "Only estimate the requests, time and download volume of the run; nothing is written")
+	platforms := fs.String("platforms", "", "Only scrape entries available on one of these comma-separated platforms (e.g. web,windows)")
+	minRatings := fs.Int("min-ratings", 0, "Only scrape entries with at least this many ratings")
+	minCoolness := fs.Int("min-coolness", 0, "Only scrape entries with at least this much coolness")
+	createdAfter := fs.String("created-after", "", "Only scrape entries submitted at or after this date or time (2006-01-02 or 2006-01-02 15:04:05)")
+	createdBefore := fs.String("created-before", "", "Only scrape entries submitted before this date or time")
+	titlePattern := fs.String("title", "", "Only scrape entries whose title matches this regular expression")
+	authorPattern := fs.String("author", "", "Only scrape entries with an author or contributor matching this regular expression")
+	sortBy := fs.String("sort", "", "Scrape entries in this order: coolness, ratings, created or title, optionally with :asc or :desc")
+	limit := fs.Int("limit", 0, "Only scrape the first N entries after filtering and sorting (0 for all)")
+	sampleSize := fs.Int("sample", 0, "Only scrape a random sample of N entries (0 to disable)")
+	sampleSeed := fs.Int64("seed", 0, "Seed for -sample, to reproduce a sample (default: random, printed at start)")
+	fs.Parse(args)
+
+	if *apiKey == "" {
+		*apiKey = os.Getenv("ITCHIO_API_KEY")
+	}
+
+	if *jamURLs == "" {
+		log.Fatal("Please provide at least one jam using the -jam flag")
+	}
+
+	// Samples are reproducible from their seed, so always report the one in use
+	if *sampleSize > 0 && *sampleSeed == 0 {
+		*sampleSeed = time.Now().UnixNano()
+	}
+	if *sampleSize > 0 {
+		fmt.Printf("Sampling %d entries per jam with seed %d\n", *sampleSize, *sampleSeed)
+	}
+
+	// Parse the size budgets
+	var sizes [3]int64
+	for i, size := range []*string{maxFileSize, maxTotalSize, minFreeDisk} {
+		if *size == "" {
+			continue
+		}
+		n, err := config.ParseByteSize(*size)
+		if err != nil {
+			log.Fatal(err)
+		}
+		sizes[i] = n
+	}
+
+	var platformList []string
+	if *platforms != "" {
+		platformList = strings.Split(*platforms, ",")
+	}
+
+	// Initialize configuration
+	cfg := config.Config{
+		OutputFormat:         *outputFormat,
+		OutputDir:            *outputDir,
+		Backend:              *storageBackend,
+		Workers:              *workers,
+		WriteWorkers:         *writeWorkers,
+		MediaWorkers:         *mediaWorkers,
+		QueueSize:            *queueSize,
+		UserAgent:            *userAgent,
+		RequestDelay:         *requestDelay,
+		RequestsPerSecond:    *requestsPerSecond,
+		Burst:                *burst,
+		CDNRequestsPerSecond: *cdnRequestsPerSecond,
+		CDNBurst:             *cdnBurst,
+		BaseURL:              *baseURL,
+		APIKey:               *apiKey,
+		APIURL:               *apiURL,
+		CookieFile:           *cookieFile,
+		MaxAttempts:          *maxAttempts,
+		RetryBaseDelay:       *retryDelay,
+		DownloadMedia:        *downloadMedia,
+		DownloadGames:        *downloadGames,
+		FetchResults:         *fetchResults,
+		FetchGamePage:        *fetchGamePage,
+		Resume:               *resume,
+		Incremental:          *incremental,
+		MaxRequests:          *maxRequests,
+		MaxFileBytes:         sizes[0],
+		MaxTotalBytes:        sizes[1],
+		MinFreeDisk:          sizes[2],
+		Platforms:            platformList,
+		MinRatings:           *minRatings,
+		MinCoolness:          *minCoolness,
+		CreatedAfter:         *createdAfter,
+		CreatedBefore:        *createdBefore,
+		TitlePattern:         *titlePattern,
+		AuthorPattern:        *authorPattern,
+		SortBy:               *sortBy,
+		Limit:                *limit,
+		SampleSize:           *sampleSize,
+		SampleSeed:           *sampleSeed,
+	}
+
+	if err := cfg.ValidateFormats(); err != nil {
+		log.Fatal(err)
+	}
+	if err := cfg.ValidateBackend(); err != nil {
+		log.Fatal(err)
+	}
+	if err := cfg.ValidateSelection(); err != nil {
+		log.Fatal(err)
+	}
+
+	// Create storage manager for the output directory, and open the backend for jam data.
+	// A dry run writes nothing, so it reads an existing database without creating one.
+	store := storage.NewManager(cfg.OutputDir)
+	backend, err := storage.OpenBackend(cfg.Backend, store, *dryRun)
+	if *dryRun && errors.Is(err, os.ErrNotExist) {
+		backend, err = store, nil // Nothing stored yet, like an empty output directory
+	}
+	if err != nil {
+		log.Fatalf("Failed to open storage backend: %v", err)
+	}
+	defer backend.Close()
+
+	// Create the fetcher shared by all jams
+	jamFetcher := fetcher.NewFetcher(cfg)
+	if cfg.CookieFile != "" {
+		if err := jamFetcher.LoadCookieFile(cfg.CookieFile); err != nil {
+			log.Fatalf("Failed to load cookie file: %v", err)
+		}
+	}
+
+	// Initialize jam processor
+	proc := processor.NewProcessor(backend, store, jamFetcher, cfg)
+
+	// Stop cleanly on Ctrl-C or SIGTERM; a second signal kills the process
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
+	// Process each jam URL. Jams run one after another; concurrency comes from the
+	// stage worker pools inside ProcessJam, and all requests share the fetcher's rate limiters.
+	urls := strings.Split(*jamURLs, ",")
+	processed, failed := 0, 0
+	var stopped error // Run budget or rate limiting that stopped the run
+	var plans []*processor.Plan
+	report := &storage.RunReport{
+		StartedAt: time.Now(),
+		Config:    cfg.Redacted(),
+	}
+
+	for _, jamURL := range urls {
+		if ctx.Err() != nil {
+			break
+		}
+
+		jamURL = strings.TrimSpace(jamURL)
+
+		// Resolve both the slug and the numeric ID of the jam
+		jam, err := jamFetcher.ResolveJam(ctx, jamURL)
+		if err != nil {
+			if ctx.Err() != nil {
+				break
+			}
+			if stopsRun(err) {
+				stopped = err
+				break
+			}
+			log.Printf("Error resolving jam %s: %v", jamURL, err)
+			report.Jams = append(report.Jams, &storage.JamReport{Input: jamURL, Outcome: storage.OutcomeFailed, Error: err.Error()})
+			failed++
+			continue
+		}
+
+		// In a dry run, only plan the jam
+		if *dryRun {
+			plan, err := proc.PlanJam(ctx, jam)
+			if err != nil {
+				if ctx.Err() != nil {
+					break
+				}
+				log.Printf("Error planning jam %s: %v", jam, err)
+				failed++
+				continue
+			}
+			printPlan(plan)
+			plans = append(plans, plan)
+			processed++
+			continue
+		}
+
+		fmt.Printf("Processing jam: %s (ID: %s)\n", jam.Slug, jam.ID)
+
+		// Process jam
+		jamReport, err := proc.ProcessJam(ctx, jam)
+		jamReport.Input = jamURL
+		report.Jams = append(report.Jams, jamReport)
+		if err != nil {
+			if ctx.Err() != nil {
+				break
+			}
+			if stopsRun(err) {
+				stopped = err
+				break
+			}
+			log.Printf("Error processing jam %s: %v", jam, err)
+			failed++
+			continue
+		}
+		processed++
+	}
+
+	code := exitCode(ctx.Err() != nil, stopped != nil, processed, failed, report.Jams)
+
+	if *dryRun {
+		if ctx.Err() != nil {
+			fmt.Printf("Interrupted: %d of %d jams planned, %d failed.\n", processed, len(urls), failed)
+		} else {
+			printPlanTotals(plans)
+		}
+		return code
+	}
+
+	// Save the run report so scheduled runs can be checked afterwards
+	stats := jamFetcher.Stats()
+	report.FinishedAt = time.Now()
+	report.DurationSeconds = report.FinishedAt.Sub(report.StartedAt).Seconds()
+	report.Requests = stats.Requests()
+	report.Retries = stats.Retries()
+	report.BytesDownloaded = stats.Bytes()
+	report.ExitCode = code
+	switch {
+	case ctx.Err() != nil:
+		report.Outcome = storage.OutcomeInterrupted
+		report.StopReason = "interrupted by signal"
+	case stopped != nil:
+		report.Outcome = storage.OutcomeStopped
+		report.StopReason = stopped.Error()
+	case code == exitFailed:
+		report.Outcome = storage.OutcomeFailed
+	case code == exitPartial:
+		report.Outcome = storage.OutcomePartial
+	default:
+		report.Outcome = storage.OutcomeDone
+	}
+	if err := store.SaveRunReport(report); err != nil {
+		log.Printf("Warning: Failed to save run report: %v", err)
+	} else {
+		fmt.Printf("Run report: %s\n", store.RunReportPath(report.StartedAt))
+	}
+
+	switch {
+	case ctx.Err() != nil:
+		fmt.Printf("Interrupted: %d of %d jams processed, %d failed. Run again with -resume to continue.\n", processed, len(urls), failed)
+	case stopped != nil:
+		hint := "Run again with -resume and a larger budget to continue."
+		if errors.Is(stopped, fetcher.ErrRateLimited) {
+			hint = "itch.io is rate limiting; run again later with -resume to continue."
+		}
+		fmt.Printf("Stopped: %v. %d of %d jams processed, %d failed. %s\n", stopped, processed, len(urls), failed, hint)
+	case code == exitFailed:
+		fmt.Printf("No jams were processed: %d failed.\n", failed)
+	case code == exitPartial:
+		fmt.Printf("Finished with errors: %d of %d jams processed, %d failed, %d games failed.\n", processed, len(urls), failed, failedGames(report.Jams))
+	default:
+		fmt.Println("All jams processed successfully!")
+	}
+
+	return code
+}
+
+// stopsRun reports whether an error ends the whole run early: a run budget that ran out,
+// or itch.io still rate limiting after every retry
+func stopsRun(err error) bool {
+	return errors.Is(err, fetcher.ErrBudgetExhausted) || errors.Is(err, fetcher.ErrRateLimited)
+}
+
+// exitCode picks the exit code of a run
+func exitCode(interrupted, stopped bool, processed, failed int, jams []*storage.JamReport) int {
+	switch {
+	case interrupted || stopped:
+		return exitIncomplete
+	case processed == 0 && failed > 0:
+		return exitFailed
+	case failed > 0 || failedGames(jams) > 0:
+		return exitPartial
+	}
+	return exitOK
+}
+
+// failedGames counts the failed games of all jams in a run
+func failedGames(jams []*storage.JamReport) int {
+	n := 0
+	for _, jam := range jams {
+		n += jam.Failed
+	}
+	return n
+}
+
+// printPlan prints the estimate of a dry run for one jam
+func printPlan(plan *processor.Plan) {
+	fmt.Printf("Jam %s (%s)\n", plan.JamID, plan.Title)
+	fmt.Printf("  Entries:         %d selected of %d", plan.Selected, plan.Entries)
+	if plan.Skipped > 0 || plan.Unchanged > 0 {
+		fmt.Printf(" (%d finished earlier, %d unchanged)", plan.Skipped, plan.Unchanged)
+	}
+	fmt.Println()
+	fmt.Printf("  Page requests:   %d\n", plan.PageRequests)
+	fmt.Printf("  Downloads:       %d media, %d game files\n", plan.MediaDownloads, plan.FileDownloads)
+	fmt.Printf("  Download volume: %s known, %d downloads of unknown size\n", formatBytes(plan.KnownBytes), plan.UnknownSizes)
+	if plan.UnknownGames > 0 {
+		fmt.Printf("  Not scraped yet: %d games, whose screenshots and files are only found while scraping\n", plan.UnknownGames)
+	}
+	fmt.Printf("  Time:            at least %s (pages %s, downloads %s)\n",
+		roundSeconds(plan.EstimatedRunSeconds), roundSeconds(plan.PageSeconds), roundSeconds(plan.DownloadSeconds))
+}
+
+// printPlanTotals prints the sum of the dry-run estimates of all jams
+func printPlanTotals(plans []*processor.Plan) {
+	var requests, downloads, unknown int
+	var bytes int64
+	var seconds float64
+	for _, plan := range plans {
+		requests += plan.PageRequests
+		downloads += plan.MediaDownloads + plan.FileDownloads
+		unknown += plan.UnknownSizes
+		bytes += plan.KnownBytes
+		seconds += plan.EstimatedRunSeconds // Jams run one after another
+	}
+	fmt.Printf("Dry run of %d jams: %d page requests, %d downloads, %s known (%d of unknown size), at least %s. Nothing was written.\n",
+		len(plans), requests, downloads, formatBytes(bytes), unknown, roundSeconds(seconds))
+}
+
+// roundSeconds converts an estimate in seconds to a duration rounded to the second
+func roundSeconds(seconds float64) time.Duration {
+	return time.Duration(seconds * float64(time.Second)).Round(time.Second)
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"syscall"
+	"time"
+
+	"Itchalyser/storage"
+)
+
+// indexPage is the template of the list of jams served by serve
+var indexPage = template.Must(template.New("index").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head><meta charset="utf-8"><title>Itchalyser</title>
+<style>body { font-family: sans-serif; max-width: 960px; margin: 2em auto; padding: 0 1em; } td, th { padding: 4px 8px; text-align: left; }</style>
+</head>
+<body>
+<h1>Scraped jams</h1>
+<table>
+<tr><th>Jam</th><th>Title</th><th>Submissions</th><th>Data</th></tr>
+{{range .}}<tr><td><a href="/jams/{{.JamID}}">{{.JamID}}</a></td><td>{{.Title}}</td><td>{{.Submissions}}</td><td><a href="/api/jams/{{.JamID}}/submissions">submissions</a>, <a href="/api/jams/{{.JamID}}/stats">stats</a></td></tr>
+{{end}}</table>
+</body>
+</html>
+`))
+
+// runServe is the serve command. It serves the scraped jams of the output directory
+// over HTTP: an HTML report per jam, JSON endpoints and the downloaded media. Data is
+// read on every request, so a scrape running at the same time shows up on reload.
+func runServe(args []string) int {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	dir := fs.String("dir", "../data", "Output directory of earlier scrapes")
+	backendKind := addBackendFlag(fs)
+	addr := fs.String("addr", "127.0.0.1:8080", "Address to listen on")
+	fs.Parse(args)
+
+	_, backend := openBackend(*dir, *backendKind)
+	defer backend.Close()
+
+	server := &http.Server{
+		Addr:              *addr,
+		Handler:           newArchiveHandler(backend, *dir),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	// Stop cleanly on Ctrl-C or SIGTERM
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+
+	fmt.Printf("Serving %s on http://%s\n", *dir, *addr)
+	if err := server.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		log.Printf("Error serving: %v", err)
+		return exitFailed
+	}
+	return exitOK
+}
+
+// newArchiveHandler returns the HTTP handler serving the jams of a backend, with the
+// media and files of its output directory
+func newArchiveHandler(backend storage.Backend, dir string) http.Handler {
+	mux := http.NewServeMux()
+
+	// openJam opens the jam named in the request, answering 404 for jams that are not stored
+	openJam := func(w http.ResponseWriter, r *http.Request) *storage.Jam {
+		jamIDs, err := backend.ListJams()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return nil
+		}
+		jamID := r.PathValue("jam")
+		if !slices.Contains(jamIDs, jamID) {
+			http.NotFound(w, r)
+			return nil
+		}
+
+		jam, err := storage.OpenJam(backend, jamID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return nil
+		}
+		return jam
+	}
+
+	listStats := func(w http.ResponseWriter) []*storage.JamStats {
+		jams, err := storage.OpenJams(backend, nil)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return nil
+		}
+		stats := make([]*storage.JamStats, 0, len(jams))
+		for _, jam := range jams {
+			jamStats, err := jam.Stats()
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return nil
+			}
+			stats = append(stats, jamStats)
+		}
+		return stats
+	}
+
+	mux.HandleFunc("GET /{$}", func(w http.ResponseWriter, r *http.Request) {
+		if stats := listStats(w); stats != nil {
+			indexPage.Execute(w, stats)
+		}
+	})
+	mux.HandleFunc("GET /jams/{jam}", func(w http.ResponseWriter, r *http.Request) {
+		jam := openJam(w, r)
+		if jam == nil {
+			return
+		}
+		archive, err := jam.Load()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		storage.WriteHTMLReport(w, archive, "/")
+	})
+	mux.HandleFunc("GET /api/jams", func(w http.ResponseWriter, r *http.Request) {
+		if stats := listStats(w); stats != nil {
+			writeJSON(w, stats)
+		}
+	})
+	mux.HandleFunc("GET /api/jams/{jam}", func(w http.ResponseWriter, r *http.Request) {
+		if jam := openJam(w, r); jam != nil {
+			writeJSON(w, struct {
+				Metadata any `json:"metadata"`
+				Results  any `json:"results,omitempty"`
+			}{jam.Metadata, jam.Results})
+		}
+	})
+	mux.HandleFunc("GET /api/jams/{jam}/submissions", func(w http.ResponseWriter, r *http.Request) {
+		// Streamed as a JSON array, so large jams are never held in memory
+		if jam := openJam(w, r); jam != nil {
+			w.Header().Set("Content-Type", "application/json")
+			storage.WriteJSON(w, []*storage.Jam{jam})
+		}
+	})
+	mux.HandleFunc("GET /api/jams/{jam}/stats", func(w http.ResponseWriter, r *http.Request) {
+		jam := openJam(w, r)
+		if jam == nil {
+			return
+		}
+		stats, err := jam.Stats()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, stats)
+	})
+
+	// Media and game files, at the paths recorded in game.json
+	mux.Handle("GET /games/", http.StripPrefix("/games/", http.FileServer(http.Dir(filepath.Join(dir, "games")))))
+
+	return mux
+}
+
+// writeJSON answers a request with a JSON document
+func writeJSON(w http.ResponseWriter, obj any) {
+	w.Header().Set("Content-Type", "application/json")
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(obj)
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"Itchalyser/storage"
+)
+
+// runStats is the stats command. It prints statistics of scraped jams from the output
+// directory without touching the network.
+func runStats(args []string) int {
+	fs := flag.NewFlagSet("stats", flag.ExitOnError)
+	archive := addArchiveFlags(fs)
+	asJSON := fs.Bool("json", false, "Print the statistics as JSON")
+	top := fs.Int("top", 10, "Number of platforms, engines, genres and tags to list")
+	fs.Parse(args)
+
+	_, backend, jams := archive.open()
+	defer backend.Close()
+
+	stats := make([]*storage.JamStats, 0, len(jams))
+	for _, jam := range jams {
+		jamStats, err := jam.Stats()
+		if err != nil {
+			log.Printf("Error reading jam %s: %v", jam.ID, err)
+			return exitFailed
+		}
+		stats = append(stats, jamStats)
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(stats); err != nil {
+			log.Printf("Error writing statistics: %v", err)
+			return exitFailed
+		}
+		return exitOK
+	}
+
+	for _, jam := range stats {
+		printStats(jam, *top)
+	}
+	return exitOK
+}
+
+// printStats prints the statistics of one jam
+func printStats(stats *storage.JamStats, top int) {
+	fmt.Printf("Jam %s (%s)\n", stats.JamID, stats.Title)
+	fmt.Printf("  Submissions: %d (%d ranked)\n", stats.Submissions, stats.Ranked)
+	fmt.Printf("  Ratings:     %d\n", stats.Ratings)
+	fmt.Printf("  Comments:    %d\n", stats.Comments)
+	fmt.Printf("  Media:       %d files, %s (%d screenshots listed)\n", stats.MediaFiles, formatBytes(stats.MediaBytes), stats.Screenshots)
+	fmt.Printf("  Game files:  %d files, %s\n", stats.GameFiles, formatBytes(stats.GameBytes))
+	printCounts("Platforms", stats.Platforms, top)
+	printCounts("Engines", stats.Engines, top)
+	printCounts("Genres", stats.Genres, top)
+	printCounts("Tags", stats.Tags, top)
+	fmt.Println()
+}
+
+// printCounts prints the most common values of a field on one line
+func printCounts(label string, counts []storage.Count, top int) {
+	if len(counts) == 0 {
+		return
+	}
+	if top > 0 && len(counts) > top {
+		counts = counts[:top]
+	}
+
+	items := make([]string, 0, len(counts))
+	for _, count := range counts {
+		items = append(items, fmt.Sprintf("%s (%d)", count.Name, count.Count))
+	}
+	fmt.Printf("  %-12s %s\n", label+":", strings.Join(items, ", "))
+}
@@ -0,0 +1,75 @@
+package storage
+
+import (
+	"fmt"
+	"iter"
+	"os"
+	"path/filepath"
+
+	"Itchalyser/config"
+	"Itchalyser/fetcher"
+)
+
+// Backend stores the structured data of scraped jams: jam metadata and results,
+// submissions with their comments, and the media manifests of games. Media and game
+// files themselves are always kept in the output directory, see Manager.
+type Backend interface {
+	// ListJams returns the IDs of the stored jams, in sorted order
+	ListJams() ([]string, error)
+	SaveJamMetadata(jamID string, metadata *fetcher.JamMetadata) error
+	LoadJamMetadata(jamID string) (*fetcher.JamMetadata, error)
+	SaveJamResults(jamID string, results *fetcher.JamResults) error
+	// LoadJamResults returns nil without an error for a jam that has no results
+	LoadJamResults(jamID string) (*fetcher.JamResults, error)
+
+	SaveGameSubmission(jamID, gameID string, game *fetcher.GameSubmission) error
+	LoadGameSubmission(jamID, gameID string) (*fetcher.GameSubmission, error)
+	// ListSubmissions returns the game IDs of the submissions stored for a jam, in numeric order
+	ListSubmissions(jamID string) ([]string, error)
+	// Submissions iterates over the submissions of a jam, reading one at a time. A
+	// submission that cannot be read yields an error, and iteration goes on with the next.
+	Submissions(jamID string) iter.Seq2[*fetcher.GameSubmission, error]
+	// LoadComments loads the comments of one submission without the rest of it
+	LoadComments(jamID, gameID string) ([]fetcher.Comment, error)
+
+	// Media manifests belong to games rather than submissions, as media is shared by
+	// every jam a game was entered in
+	SaveMediaManifest(gameID string, manifest *MediaManifest) error
+	LoadMediaManifest(gameID string) (*MediaManifest, error)
+
+	Close() error
+}
+
+// The output directory of JSON files is the default backend
+var _ Backend = (*Manager)(nil)
+
+// DatabaseFile is the name of the SQLite database in the output directory
+const DatabaseFile = "itchalyser.db"
+
+// OpenBackend opens the backend of the given kind for the output directory of files.
+// A read-only SQLite backend fails with an error matching os.ErrNotExist when the
+// directory has no database yet.
+func OpenBackend(kind string, files *Manager, readOnly bool) (Backend, error) {
+	switch kind {
+	case config.BackendFiles:
+		return files, nil
+	case config.BackendSQLite:
+		path := filepath.Join(files.baseDir, DatabaseFile)
+		if readOnly && !fileExists(path) {
+			return nil, fmt.Errorf("no database at %s: %w", path, os.ErrNotExist)
+		}
+		if !readOnly {
+			if err := files.CreateDirectory(files.baseDir); err != nil {
+				return nil, err
+			}
+		}
+		return OpenDatabase(path, readOnly)
+	}
+	return nil, fmt.Errorf("unsupported storage backend: %s", kind)
+}
+
+// notStored returns the error for data that is missing from a backend, which matches
+// os.ErrNotExist like a missing file of the files backend
+func notStored(format string, args ...any) error {
+	return fmt.Errorf(format+": %w", append(args, os.ErrNotExist)...)
+}
@@ -0,0 +1,154 @@
+package storage
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"Itchalyser/fetcher"
+)
+
+// roundTrip is everything read back from a backend after saving the fixture jam
+type roundTrip struct {
+	Jams       []string
+	Metadata   *fetcher.JamMetadata
+	Results    *fetcher.JamResults
+	Submission *fetcher.GameSubmission
+	Listed     []string
+	Iterated   []*fetcher.GameSubmission
+	Comments   []fetcher.Comment
+	Manifest   *MediaManifest
+}
+
+func fixtureSubmission() *fetcher.GameSubmission {
+	return &fetcher.GameSubmission{
+		ID:          "101",
+		Title:       "Alpha",
+		URL:         "https://ann.itch.io/alpha",
+		Description: "A game about a cube",
+		Authors:     []fetcher.User{{Name: "ann", ID: 7, URL: "https://ann.itch.io"}},
+		Platforms:   []string{"windows", "html"},
+		CreatedAt:   "2025-03-01 12:00:00",
+		Coolness:    15,
+		RatingCount: 12,
+		Cover:       fetcher.CoverImage{URL: "https://img.itch.zone/cover.png", Color: "#112233"},
+		Screenshots: []string{"https://img.itch.zone/shot1.png", "https://img.itch.zone/shot2.png"},
+		Downloads: []fetcher.Download{
+			{Filename: "alpha-win.zip", Size: "12 MB", Platforms: []string{"windows"}, UploadDate: "2025-03-01", UploadID: 900, Path: "games/101/files/alpha-win.zip", Bytes: 12582912, SHA256: "abc123"},
+			{Filename: "alpha-linux.tar.gz", Size: "11 MB", Platforms: []string{"linux", "osx"}, UploadID: 901},
+		},
+		Comments: []fetcher.Comment{
+			{ID: 1, Author: "bob", AuthorURL: "https://bob.itch.io", Content: "Great game", Timestamp: "2025-03-02T10:00:00Z", Ratings: map[string]int{"Fun": 5, "Theme": 4}},
+			{ID: 2, ParentID: 1, Author: "ann", AuthorURL: "https://ann.itch.io", IsDeveloper: true, Content: "Thanks!", Timestamp: "2025-03-02T11:00:00Z"},
+		},
+		CriteriaResponses: map[string]string{"Did you use AI?": "No"},
+		Status:            "Released",
+		Genre:             "Puzzle",
+		Tags:              []string{"cube", "short"},
+		MadeWith:          []string{"Godot"},
+		AverageSession:    "A few minutes",
+		Inputs:            []string{"Keyboard"},
+		Accessibility:     []string{"Color-blind friendly"},
+		Languages:         []string{"English"},
+		License:           "MIT",
+		GameInfo:          map[string]string{"Genre": "Puzzle", "Made with": "Godot"},
+		Results: &fetcher.EntryResult{
+			GameID: 101, RateURL: "https://itch.io/jam/fixture-jam/rate/101", Title: "Alpha",
+			Rank: 2, Score: 4, RawScore: 4.1, RatingCount: 12,
+			Criteria: []fetcher.CriterionResult{{Name: "Fun", Rank: 1, Score: 4.5, RawScore: 4.6}},
+		},
+		MediaDir:     "games/101/media",
+		FieldSources: map[string]string{"description": "selector", "authors": "json-ld"},
+		FetchErrors:  []string{"screenshot 3: HTTP 500"},
+		Skipped:      []string{"file big.zip: over the size budget"},
+	}
+}
+
+// saveAndLoad saves the fixture jam to a backend and reads every part of it back
+func saveAndLoad(t *testing.T, backend Backend) roundTrip {
+	t.Helper()
+	const jamID = "fixture-jam"
+
+	metadata := &fetcher.JamMetadata{
+		ID: jamID, Title: "Fixture Jam", Hosts: []fetcher.Host{{Name: "host", URL: "https://host.itch.io"}},
+		StartDate: "2025-02-20", EndDate: "2025-03-01", Theme: "Cubes", SubmissionCount: "2",
+		InternalID: "4242", FieldSources: map[string]string{"title": "json-ld"},
+	}
+	results := &fetcher.JamResults{
+		JamID:    jamID,
+		Criteria: []string{"Overall", "Fun"},
+		Entries:  []fetcher.EntryResult{*fixtureSubmission().Results},
+	}
+	manifest := &MediaManifest{Files: []MediaFile{{
+		Name: "cover.png", URL: "https://img.itch.zone/cover.png", Size: 2048, SHA256: "def456",
+		DownloadedAt: time.Date(2025, 3, 2, 9, 30, 0, 0, time.UTC),
+	}}}
+
+	if err := backend.SaveJamMetadata(jamID, metadata); err != nil {
+		t.Fatalf("SaveJamMetadata: %v", err)
+	}
+	if err := backend.SaveJamResults(jamID, results); err != nil {
+		t.Fatalf("SaveJamResults: %v", err)
+	}
+	if err := backend.SaveGameSubmission(jamID, "101", fixtureSubmission()); err != nil {
+		t.Fatalf("SaveGameSubmission: %v", err)
+	}
+	if err := backend.SaveMediaManifest("101", manifest); err != nil {
+		t.Fatalf("SaveMediaManifest: %v", err)
+	}
+
+	var got roundTrip
+	var err error
+	if got.Jams, err = backend.ListJams(); err != nil {
+		t.Fatalf("ListJams: %v", err)
+	}
+	if got.Metadata, err = backend.LoadJamMetadata(jamID); err != nil {
+		t.Fatalf("LoadJamMetadata: %v", err)
+	}
+	if got.Results, err = backend.LoadJamResults(jamID); err != nil {
+		t.Fatalf("LoadJamResults: %v", err)
+	}
+	if got.Submission, err = backend.LoadGameSubmission(jamID, "101"); err != nil {
+		t.Fatalf("LoadGameSubmission: %v", err)
+	}
+	if got.Listed, err = backend.ListSubmissions(jamID); err != nil {
+		t.Fatalf("ListSubmissions: %v", err)
+	}
+	for submission, err := range backend.Submissions(jamID) {
+		if err != nil {
+			t.Fatalf("Submissions: %v", err)
+		}
+		got.Iterated = append(got.Iterated, submission)
+	}
+	if got.Comments, err = backend.LoadComments(jamID, "101"); err != nil {
+		t.Fatalf("LoadComments: %v", err)
+	}
+	if got.Manifest, err = backend.LoadMediaManifest("101"); err != nil {
+		t.Fatalf("LoadMediaManifest: %v", err)
+	}
+
+	if !reflect.DeepEqual(got.Submission, fixtureSubmission()) {
+		t.Errorf("submission = %+v, want %+v", got.Submission, fixtureSubmission())
+	}
+	return got
+}
+
+func TestBackendsRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	files := NewManager(filepath.Join(dir, "files"))
+
+	db, err := OpenDatabase(filepath.Join(dir, DatabaseFile), false)
+	if err != nil {
+		t.Fatalf("OpenDatabase: %v", err)
+	}
+	defer db.Close()
+
+	var fromFiles, fromDB roundTrip
+	t.Run("files", func(t *testing.T) { fromFiles = saveAndLoad(t, files) })
+	t.Run("sqlite", func(t *testing.T) { fromDB = saveAndLoad(t, db) })
+
+	if !reflect.DeepEqual(fromFiles, fromDB) {
+		t.Errorf("files and sqlite backends differ:\nfiles  %+v\nsqlite %+v", fromFiles, fromDB)
+	}
+}
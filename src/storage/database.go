@@ -0,0 +1,554 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"Itchalyser/fetcher"
+
+	_ "modernc.org/sqlite" // Registers the pure Go "sqlite" driver
+)
+
+// schemaVersion is stored in the database's user_version and raised with every change to schema
+const schemaVersion = 1
+
+// schema creates the tables of the SQLite backend. Lists and maps get tables of their
+// own, with a position column keeping the order they were scraped in, so platforms,
+// tags, engines, authors and comments can be queried and indexed directly.
+const schema = `
+CREATE TABLE IF NOT EXISTS jams (
+	id               TEXT PRIMARY KEY, -- Storage key of the jam, its slug
+	slug             TEXT NOT NULL,    -- ID field of the scraped metadata
+	internal_id      TEXT NOT NULL,
+	title            TEXT NOT NULL,
+	theme            TEXT NOT NULL,
+	start_date       TEXT NOT NULL,
+	end_date         TEXT NOT NULL,
+	submission_date  TEXT NOT NULL,
+	submission_count TEXT NOT NULL,
+	rating_count     TEXT NOT NULL,
+	comments_count   TEXT NOT NULL,
+	cover_image_url  TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS jam_hosts (
+	jam_id   TEXT NOT NULL REFERENCES jams (id) ON DELETE CASCADE,
+	position INTEGER NOT NULL,
+	name     TEXT NOT NULL,
+	url      TEXT NOT NULL,
+	PRIMARY KEY (jam_id, position)
+);
+
+CREATE TABLE IF NOT EXISTS jam_field_sources (
+	jam_id TEXT NOT NULL REFERENCES jams (id) ON DELETE CASCADE,
+	field  TEXT NOT NULL,
+	source TEXT NOT NULL,
+	PRIMARY KEY (jam_id, field)
+);
+
+CREATE TABLE IF NOT EXISTS jam_criteria (
+	jam_id   TEXT NOT NULL REFERENCES jams (id) ON DELETE CASCADE,
+	position INTEGER NOT NULL,
+	name     TEXT NOT NULL,
+	PRIMARY KEY (jam_id, position)
+);
+
+CREATE TABLE IF NOT EXISTS results (
+	jam_id       TEXT NOT NULL REFERENCES jams (id) ON DELETE CASCADE,
+	game_id      INTEGER NOT NULL, -- From the entry's rate link, /rate/{game ID}
+	position     INTEGER NOT NULL, -- Order on the results pages
+	rate_url     TEXT NOT NULL,
+	title        TEXT NOT NULL,
+	rank         INTEGER NOT NULL,
+	score        REAL NOT NULL,
+	raw_score    REAL NOT NULL,
+	rating_count INTEGER NOT NULL,
+	PRIMARY KEY (jam_id, game_id)
+);
+CREATE INDEX IF NOT EXISTS results_rank ON results (jam_id, rank);
+
+CREATE TABLE IF NOT EXISTS result_criteria (
+	jam_id    TEXT NOT NULL,
+	game_id   INTEGER NOT NULL,
+	position  INTEGER NOT NULL,
+	name      TEXT NOT NULL,
+	rank      INTEGER NOT NULL,
+	score     REAL NOT NULL,
+	raw_score REAL NOT NULL,
+	PRIMARY KEY (jam_id, game_id, position),
+	FOREIGN KEY (jam_id, game_id) REFERENCES results (jam_id, game_id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS result_criteria_rank ON result_criteria (jam_id, name, rank);
+
+CREATE TABLE IF NOT EXISTS submissions (
+	jam_id          TEXT NOT NULL REFERENCES jams (id) ON DELETE CASCADE,
+	game_id         TEXT NOT NULL,
+	result_game_id  INTEGER, -- Row in results, NULL for unranked submissions
+	title           TEXT NOT NULL,
+	url             TEXT NOT NULL,
+	description     TEXT NOT NULL,
+	created_at      TEXT NOT NULL,
+	coolness        INTEGER NOT NULL,
+	rating_count    INTEGER NOT NULL,
+	cover_url       TEXT NOT NULL,
+	cover_color     TEXT NOT NULL,
+	status          TEXT NOT NULL,
+	genre           TEXT NOT NULL,
+	average_session TEXT NOT NULL,
+	license         TEXT NOT NULL,
+	media_dir       TEXT NOT NULL,
+	PRIMARY KEY (jam_id, game_id)
+);
+CREATE INDEX IF NOT EXISTS submissions_game ON submissions (game_id);
+CREATE INDEX IF NOT EXISTS submissions_rating_count ON submissions (rating_count);
+CREATE INDEX IF NOT EXISTS submissions_genre ON submissions (genre COLLATE NOCASE);
+
+CREATE TABLE IF NOT EXISTS submission_authors (
+	jam_id   TEXT NOT NULL,
+	game_id  TEXT NOT NULL,
+	position INTEGER NOT NULL,
+	user_id  INTEGER NOT NULL,
+	name     TEXT NOT NULL,
+	url      TEXT NOT NULL,
+	PRIMARY KEY (jam_id, game_id, position),
+	FOREIGN KEY (jam_id, game_id) REFERENCES submissions (jam_id, game_id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS submission_authors_name ON submission_authors (name COLLATE NOCASE);
+
+-- List fields of submissions, one row per value: platforms, screenshots, tags,
+-- made_with, inputs, accessibility, languages, fetch_errors and skipped
+CREATE TABLE IF NOT EXISTS submission_values (
+	jam_id   TEXT NOT NULL,
+	game_id  TEXT NOT NULL,
+	field    TEXT NOT NULL,
+	position INTEGER NOT NULL,
+	value    TEXT NOT NULL,
+	PRIMARY KEY (jam_id, game_id, field, position),
+	FOREIGN KEY (jam_id, game_id) REFERENCES submissions (jam_id, game_id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS submission_values_value ON submission_values (field, value COLLATE NOCASE);
+
+-- Map fields of submissions, one row per key: criteria_responses, game_info and field_sources
+CREATE TABLE IF NOT EXISTS submission_fields (
+	jam_id  TEXT NOT NULL,
+	game_id TEXT NOT NULL,
+	field   TEXT NOT NULL,
+	key     TEXT NOT NULL,
+	value   TEXT NOT NULL,
+	PRIMARY KEY (jam_id, game_id, field, key),
+	FOREIGN KEY (jam_id, game_id) REFERENCES submissions (jam_id, game_id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS downloads (
+	jam_id      TEXT NOT NULL,
+	game_id     TEXT NOT NULL,
+	position    INTEGER NOT NULL,
+	filename    TEXT NOT NULL,
+	size        TEXT NOT NULL,
+	upload_date TEXT NOT NULL,
+	upload_id   INTEGER NOT NULL,
+	url         TEXT NOT NULL,
+	path        TEXT NOT NULL,
+	bytes       INTEGER NOT NULL,
+	sha256      TEXT NOT NULL,
+	PRIMARY KEY (jam_id, game_id, position),
+	FOREIGN KEY (jam_id, game_id) REFERENCES submissions (jam_id, game_id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS download_platforms (
+	jam_id   TEXT NOT NULL,
+	game_id  TEXT NOT NULL,
+	download INTEGER NOT NULL, -- Position of the download
+	position INTEGER NOT NULL,
+	platform TEXT NOT NULL,
+	PRIMARY KEY (jam_id, game_id, download, position),
+	FOREIGN KEY (jam_id, game_id, download) REFERENCES downloads (jam_id, game_id, position) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS comments (
+	jam_id       TEXT NOT NULL,
+	game_id      TEXT NOT NULL,
+	position     INTEGER NOT NULL,
+	comment_id   INTEGER NOT NULL,
+	parent_id    INTEGER NOT NULL,
+	author       TEXT NOT NULL,
+	author_url   TEXT NOT NULL,
+	is_developer INTEGER NOT NULL,
+	content      TEXT NOT NULL,
+	timestamp    TEXT NOT NULL,
+	PRIMARY KEY (jam_id, game_id, position),
+	FOREIGN KEY (jam_id, game_id) REFERENCES submissions (jam_id, game_id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS comments_author_url ON comments (author_url);
+
+CREATE TABLE IF NOT EXISTS comment_ratings (
+	jam_id    TEXT NOT NULL,
+	game_id   TEXT NOT NULL,
+	comment   INTEGER NOT NULL, -- Position of the comment
+	criterion TEXT NOT NULL,
+	stars     INTEGER NOT NULL,
+	PRIMARY KEY (jam_id, game_id, comment, criterion),
+	FOREIGN KEY (jam_id, game_id, comment) REFERENCES comments (jam_id, game_id, position) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS media_manifests (
+	game_id TEXT PRIMARY KEY
+);
+
+CREATE TABLE IF NOT EXISTS media_files (
+	game_id       TEXT NOT NULL REFERENCES media_manifests (game_id) ON DELETE CASCADE,
+	position      INTEGER NOT NULL,
+	name          TEXT NOT NULL,
+	url           TEXT NOT NULL,
+	size          INTEGER NOT NULL,
+	sha256        TEXT NOT NULL,
+	downloaded_at TEXT NOT NULL,
+	PRIMARY KEY (game_id, position)
+);
+`
+
+// Database is the SQLite backend. Jam data is stored in a normalized schema, so a
+// question across jams, such as every Godot game with more than 50 ratings, is one
+// query instead of a walk over every game.json.
+type Database struct {
+	db   *sql.DB
+	path string
+}
+
+var _ Backend = (*Database)(nil)
+
+// OpenDatabase opens the SQLite database at path, creating it and its tables when it
+// is opened for writing
+func OpenDatabase(path string, readOnly bool) (*Database, error) {
+	dsn := "file:" + filepath.ToSlash(path) + "?_pragma=foreign_keys(1)&_pragma=busy_timeout(10000)"
+	if readOnly {
+		dsn += "&mode=ro"
+	} else {
+		// WAL lets the offline commands read while a scrape is writing
+		dsn += "&_pragma=journal_mode(WAL)"
+	}
+
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, err
+	}
+	// SQLite allows one writer at a time; a single connection queues the write workers
+	// instead of having them fail with "database is locked"
+	db.SetMaxOpenConns(1)
+
+	d := &Database{db: db, path: path}
+	if err := d.migrate(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return d, nil
+}
+
+// migrate creates the schema of a new database and checks the version of an existing one
+func (d *Database) migrate() error {
+	var version int
+	if err := d.db.QueryRow("PRAGMA user_version").Scan(&version); err != nil {
+		return err
+	}
+
+	switch {
+	case version == schemaVersion:
+		return nil
+	case version > schemaVersion:
+		return fmt.Errorf("database schema version %d is newer than this version of Itchalyser supports (%d)", version, schemaVersion)
+	}
+
+	return d.update(func(w *txWriter) {
+		w.exec(schema)
+		w.exec(fmt.Sprintf("PRAGMA user_version = %d", schemaVersion))
+	})
+}
+
+// Close closes the database
+func (d *Database) Close() error {
+	return d.db.Close()
+}
+
+// ListJams returns the IDs of the jams in the database, in sorted order
+func (d *Database) ListJams() ([]string, error) {
+	var jamIDs []string
+	err := d.view(func(tx *sql.Tx) error {
+		return queryRows(tx, func(rows *sql.Rows) error {
+			var jamID string
+			if err := rows.Scan(&jamID); err != nil {
+				return err
+			}
+			jamIDs = append(jamIDs, jamID)
+			return nil
+		}, "SELECT id FROM jams ORDER BY id")
+	})
+	return jamIDs, err
+}
+
+// SaveJamMetadata saves the metadata of a jam, keeping its results and submissions
+func (d *Database) SaveJamMetadata(jamID string, metadata *fetcher.JamMetadata) error {
+	return d.update(func(w *txWriter) {
+		w.exec(`INSERT INTO jams (id, slug, internal_id, title, theme, start_date, end_date, submission_date,
+				submission_count, rating_count, comments_count, cover_image_url)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			ON CONFLICT (id) DO UPDATE SET slug = excluded.slug, internal_id = excluded.internal_id,
+				title = excluded.title, theme = excluded.theme, start_date = excluded.start_date,
+				end_date = excluded.end_date, submission_date = excluded.submission_date,
+				submission_count = excluded.submission_count, rating_count = excluded.rating_count,
+				comments_count = excluded.comments_count, cover_image_url = excluded.cover_image_url`,
+			jamID, metadata.ID, metadata.InternalID, metadata.Title, metadata.Theme, metadata.StartDate,
+			metadata.EndDate, metadata.SubmissionDate, metadata.SubmissionCount, metadata.RatingCount,
+			metadata.CommentsCount, metadata.CoverImageURL)
+
+		w.exec("DELETE FROM jam_hosts WHERE jam_id = ?", jamID)
+		for i, host := range metadata.Hosts {
+			w.exec("INSERT INTO jam_hosts (jam_id, position, name, url) VALUES (?, ?, ?, ?)", jamID, i, host.Name, host.URL)
+		}
+
+		w.exec("DELETE FROM jam_field_sources WHERE jam_id = ?", jamID)
+		for field, source := range metadata.FieldSources {
+			w.exec("INSERT INTO jam_field_sources (jam_id, field, source) VALUES (?, ?, ?)", jamID, field, source)
+		}
+	})
+}
+
+// LoadJamMetadata loads the metadata of a jam
+func (d *Database) LoadJamMetadata(jamID string) (*fetcher.JamMetadata, error) {
+	var metadata fetcher.JamMetadata
+	err := d.view(func(tx *sql.Tx) error {
+		err := tx.QueryRow(`SELECT slug, internal_id, title, theme, start_date, end_date, submission_date,
+				submission_count, rating_count, comments_count, cover_image_url
+			FROM jams WHERE id = ?`, jamID).
+			Scan(&metadata.ID, &metadata.InternalID, &metadata.Title, &metadata.Theme, &metadata.StartDate,
+				&metadata.EndDate, &metadata.SubmissionDate, &metadata.SubmissionCount, &metadata.RatingCount,
+				&metadata.CommentsCount, &metadata.CoverImageURL)
+		if errors.Is(err, sql.ErrNoRows) {
+			return notStored("jam %s is not in the database", jamID)
+		}
+		if err != nil {
+			return err
+		}
+
+		err = queryRows(tx, func(rows *sql.Rows) error {
+			var host fetcher.Host
+			if err := rows.Scan(&host.Name, &host.URL); err != nil {
+				return err
+			}
+			metadata.Hosts = append(metadata.Hosts, host)
+			return nil
+		}, "SELECT name, url FROM jam_hosts WHERE jam_id = ? ORDER BY position", jamID)
+		if err != nil {
+			return err
+		}
+
+		return queryRows(tx, func(rows *sql.Rows) error {
+			var field, source string
+			if err := rows.Scan(&field, &source); err != nil {
+				return err
+			}
+			if metadata.FieldSources == nil {
+				metadata.FieldSources = make(map[string]string)
+			}
+			metadata.FieldSources[field] = source
+			return nil
+		}, "SELECT field, source FROM jam_field_sources WHERE jam_id = ?", jamID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &metadata, nil
+}
+
+// SaveJamResults replaces the results of a jam
+func (d *Database) SaveJamResults(jamID string, results *fetcher.JamResults) error {
+	return d.update(func(w *txWriter) {
+		w.exec("DELETE FROM results WHERE jam_id = ?", jamID)
+		w.exec("DELETE FROM jam_criteria WHERE jam_id = ?", jamID)
+		for i, name := range results.Criteria {
+			w.exec("INSERT INTO jam_criteria (jam_id, position, name) VALUES (?, ?, ?)", jamID, i, name)
+		}
+		for i := range results.Entries {
+			saveEntryResult(w, jamID, i, &results.Entries[i])
+		}
+	})
+}
+
+// saveEntryResult inserts or replaces the result of one entry. A position below zero
+// keeps the position of an existing row, or places a new one after the others.
+func saveEntryResult(w *txWriter, jamID string, position int, entry *fetcher.EntryResult) {
+	w.exec(`INSERT INTO results (jam_id, game_id, position, rate_url, title, rank, score, raw_score, rating_count)
+		VALUES (?1, ?2, CASE WHEN ?3 >= 0 THEN ?3 ELSE (SELECT COALESCE(MAX(position) + 1, 0) FROM results WHERE jam_id = ?1) END,
+			?4, ?5, ?6, ?7, ?8, ?9)
+		ON CONFLICT (jam_id, game_id) DO UPDATE SET
+			position = CASE WHEN ?3 >= 0 THEN ?3 ELSE position END,
+			rate_url = excluded.rate_url, title = excluded.title, rank = excluded.rank, score = excluded.score,
+			raw_score = excluded.raw_score, rating_count = excluded.rating_count`,
+		jamID, entry.GameID, position, entry.RateURL, entry.Title, entry.Rank, entry.Score, entry.RawScore, entry.RatingCount)
+
+	w.exec("DELETE FROM result_criteria WHERE jam_id = ? AND game_id = ?", jamID, entry.GameID)
+	for i, criterion := range entry.Criteria {
+		w.exec(`INSERT INTO result_criteria (jam_id, game_id, position, name, rank, score, raw_score)
+			VALUES (?, ?, ?, ?, ?, ?, ?)`,
+			jamID, entry.GameID, i, criterion.Name, criterion.Rank, criterion.Score, criterion.RawScore)
+	}
+}
+
+// LoadJamResults loads the results of a jam, returning nil if it has none
+func (d *Database) LoadJamResults(jamID string) (*fetcher.JamResults, error) {
+	results := &fetcher.JamResults{JamID: jamID}
+	err := d.view(func(tx *sql.Tx) error {
+		err := queryRows(tx, func(rows *sql.Rows) error {
+			var name string
+			if err := rows.Scan(&name); err != nil {
+				return err
+			}
+			results.Criteria = append(results.Criteria, name)
+			return nil
+		}, "SELECT name FROM jam_criteria WHERE jam_id = ? ORDER BY position", jamID)
+		if err != nil {
+			return err
+		}
+
+		err = queryRows(tx, func(rows *sql.Rows) error {
+			var entry fetcher.EntryResult
+			if err := rows.Scan(&entry.GameID, &entry.RateURL, &entry.Title, &entry.Rank,
+				&entry.Score, &entry.RawScore, &entry.RatingCount); err != nil {
+				return err
+			}
+			results.Entries = append(results.Entries, entry)
+			return nil
+		}, `SELECT game_id, rate_url, title, rank, score, raw_score, rating_count
+			FROM results WHERE jam_id = ? ORDER BY position`, jamID)
+		if err != nil {
+			return err
+		}
+
+		criteria := make(map[int][]fetcher.CriterionResult)
+		err = queryRows(tx, func(rows *sql.Rows) error {
+			var gameID int
+			var criterion fetcher.CriterionResult
+			if err := rows.Scan(&gameID, &criterion.Name, &criterion.Rank, &criterion.Score, &criterion.RawScore); err != nil {
+				return err
+			}
+			criteria[gameID] = append(criteria[gameID], criterion)
+			return nil
+		}, `SELECT game_id, name, rank, score, raw_score
+			FROM result_criteria WHERE jam_id = ? ORDER BY game_id, position`, jamID)
+		for i := range results.Entries {
+			results.Entries[i].Criteria = criteria[results.Entries[i].GameID]
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(results.Criteria) == 0 && len(results.Entries) == 0 {
+		return nil, nil
+	}
+	return results, nil
+}
+
+// SaveMediaManifest replaces the media manifest of a game
+func (d *Database) SaveMediaManifest(gameID string, manifest *MediaManifest) error {
+	return d.update(func(w *txWriter) {
+		w.exec("DELETE FROM media_manifests WHERE game_id = ?", gameID)
+		w.exec("INSERT INTO media_manifests (game_id) VALUES (?)", gameID)
+		for i, file := range manifest.Files {
+			w.exec(`INSERT INTO media_files (game_id, position, name, url, size, sha256, downloaded_at)
+				VALUES (?, ?, ?, ?, ?, ?, ?)`,
+				gameID, i, file.Name, file.URL, file.Size, file.SHA256, file.DownloadedAt.Format(time.RFC3339Nano))
+		}
+	})
+}
+
+// LoadMediaManifest loads the media manifest of a game
+func (d *Database) LoadMediaManifest(gameID string) (*MediaManifest, error) {
+	manifest := &MediaManifest{}
+	err := d.view(func(tx *sql.Tx) error {
+		var found int
+		err := tx.QueryRow("SELECT 1 FROM media_manifests WHERE game_id = ?", gameID).Scan(&found)
+		if errors.Is(err, sql.ErrNoRows) {
+			return notStored("no media manifest for game %s in the database", gameID)
+		}
+		if err != nil {
+			return err
+		}
+
+		return queryRows(tx, func(rows *sql.Rows) error {
+			var file MediaFile
+			var downloadedAt string
+			if err := rows.Scan(&file.Name, &file.URL, &file.Size, &file.SHA256, &downloadedAt); err != nil {
+				return err
+			}
+			file.DownloadedAt, _ = time.Parse(time.RFC3339Nano, downloadedAt)
+			manifest.Files = append(manifest.Files, file)
+			return nil
+		}, `SELECT name, url, size, sha256, downloaded_at
+			FROM media_files WHERE game_id = ? ORDER BY position`, gameID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// view runs fn in a transaction that is rolled back afterwards, so everything it reads
+// comes from one snapshot even while a scrape is writing
+func (d *Database) view(fn func(tx *sql.Tx) error) error {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	return fn(tx)
+}
+
+// update runs fn in a transaction, committing it unless one of its statements failed
+func (d *Database) update(fn func(w *txWriter)) error {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	w := &txWriter{tx: tx}
+	fn(w)
+	if w.err != nil {
+		tx.Rollback()
+		return w.err
+	}
+	return tx.Commit()
+}
+
+// txWriter runs the statements of a write transaction, remembering the first error so
+// the statements can be listed without checking each one
+type txWriter struct {
+	tx  *sql.Tx
+	err error
+}
+
+// exec runs a statement unless an earlier one failed
+func (w *txWriter) exec(query string, args ...any) {
+	if w.err != nil {
+		return
+	}
+	_, w.err = w.tx.Exec(query, args...)
+}
+
+// queryRows runs a query and calls scan for each row of the result
+func queryRows(tx *sql.Tx, scan func(rows *sql.Rows) error, query string, args ...any) error {
+	rows, err := tx.Query(query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		if err := scan(rows); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
@@ -0,0 +1,345 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"iter"
+	"slices"
+
+	"Itchalyser/fetcher"
+)
+
+// submissionLists maps the list fields of a submission to their field names in submission_values
+func submissionLists(game *fetcher.GameSubmission) map[string]*[]string {
+	return map[string]*[]string{
+		"platforms":     &game.Platforms,
+		"screenshots":   &game.Screenshots,
+		"tags":          &game.Tags,
+		"made_with":     &game.MadeWith,
+		"inputs":        &game.Inputs,
+		"accessibility": &game.Accessibility,
+		"languages":     &game.Languages,
+		"fetch_errors":  &game.FetchErrors,
+		"skipped":       &game.Skipped,
+	}
+}
+
+// submissionMaps maps the map fields of a submission to their field names in submission_fields
+func submissionMaps(game *fetcher.GameSubmission) map[string]*map[string]string {
+	return map[string]*map[string]string{
+		"criteria_responses": &game.CriteriaResponses,
+		"game_info":          &game.GameInfo,
+		"field_sources":      &game.FieldSources,
+	}
+}
+
+// SaveGameSubmission replaces a submission with everything it holds. Its result is
+// saved with the jam's results, so a submission scraped before its jam's results is
+// still ranked when it is loaded.
+func (d *Database) SaveGameSubmission(jamID, gameID string, game *fetcher.GameSubmission) error {
+	return d.update(func(w *txWriter) {
+		w.exec("DELETE FROM submissions WHERE jam_id = ? AND game_id = ?", jamID, gameID)
+
+		var resultGameID any
+		if game.Results != nil {
+			saveEntryResult(w, jamID, -1, game.Results)
+			resultGameID = game.Results.GameID
+		}
+
+		w.exec(`INSERT INTO submissions (jam_id, game_id, result_game_id, title, url, description, created_at,
+				coolness, rating_count, cover_url, cover_color, status, genre, average_session, license, media_dir)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			jamID, gameID, resultGameID, game.Title, game.URL, game.Description, game.CreatedAt,
+			game.Coolness, game.RatingCount, game.Cover.URL, game.Cover.Color, game.Status, game.Genre,
+			game.AverageSession, game.License, game.MediaDir)
+
+		for i, author := range game.Authors {
+			w.exec(`INSERT INTO submission_authors (jam_id, game_id, position, user_id, name, url)
+				VALUES (?, ?, ?, ?, ?, ?)`, jamID, gameID, i, author.ID, author.Name, author.URL)
+		}
+
+		for field, values := range submissionLists(game) {
+			for i, value := range *values {
+				w.exec(`INSERT INTO submission_values (jam_id, game_id, field, position, value)
+					VALUES (?, ?, ?, ?, ?)`, jamID, gameID, field, i, value)
+			}
+		}
+		for field, values := range submissionMaps(game) {
+			for key, value := range *values {
+				w.exec(`INSERT INTO submission_fields (jam_id, game_id, field, key, value)
+					VALUES (?, ?, ?, ?, ?)`, jamID, gameID, field, key, value)
+			}
+		}
+
+		for i, download := range game.Downloads {
+			w.exec(`INSERT INTO downloads (jam_id, game_id, position, filename, size, upload_date, upload_id, url, path, bytes, sha256)
+				VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+				jamID, gameID, i, download.Filename, download.Size, download.UploadDate, download.UploadID,
+				download.URL, download.Path, download.Bytes, download.SHA256)
+			for j, platform := range download.Platforms {
+				w.exec(`INSERT INTO download_platforms (jam_id, game_id, download, position, platform)
+					VALUES (?, ?, ?, ?, ?)`, jamID, gameID, i, j, platform)
+			}
+		}
+
+		for i, comment := range game.Comments {
+			w.exec(`INSERT INTO comments (jam_id, game_id, position, comment_id, parent_id, author, author_url,
+					is_developer, content, timestamp)
+				VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+				jamID, gameID, i, comment.ID, comment.ParentID, comment.Author, comment.AuthorURL,
+				comment.IsDeveloper, comment.Content, comment.Timestamp)
+			for criterion, stars := range comment.Ratings {
+				w.exec(`INSERT INTO comment_ratings (jam_id, game_id, comment, criterion, stars)
+					VALUES (?, ?, ?, ?, ?)`, jamID, gameID, i, criterion, stars)
+			}
+		}
+	})
+}
+
+// LoadGameSubmission loads a submission with everything it holds
+func (d *Database) LoadGameSubmission(jamID, gameID string) (*fetcher.GameSubmission, error) {
+	var game *fetcher.GameSubmission
+	err := d.view(func(tx *sql.Tx) error {
+		var err error
+		game, err = loadSubmission(tx, jamID, gameID)
+		return err
+	})
+	return game, err
+}
+
+// ListSubmissions returns the game IDs of the submissions of a jam, in numeric order
+func (d *Database) ListSubmissions(jamID string) ([]string, error) {
+	var gameIDs []string
+	err := d.view(func(tx *sql.Tx) error {
+		return queryRows(tx, func(rows *sql.Rows) error {
+			var gameID string
+			if err := rows.Scan(&gameID); err != nil {
+				return err
+			}
+			gameIDs = append(gameIDs, gameID)
+			return nil
+		}, "SELECT game_id FROM submissions WHERE jam_id = ?", jamID)
+	})
+	slices.SortFunc(gameIDs, compareGameIDs)
+	return gameIDs, err
+}
+
+// Submissions iterates over the submissions of a jam in game ID order, loading one at a time
+func (d *Database) Submissions(jamID string) iter.Seq2[*fetcher.GameSubmission, error] {
+	return func(yield func(*fetcher.GameSubmission, error) bool) {
+		gameIDs, err := d.ListSubmissions(jamID)
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+
+		for _, gameID := range gameIDs {
+			game, err := d.LoadGameSubmission(jamID, gameID)
+			if err != nil {
+				if !yield(nil, err) {
+					return
+				}
+				continue
+			}
+			if !yield(game, nil) {
+				return
+			}
+		}
+	}
+}
+
+// LoadComments loads the comments of a submission
+func (d *Database) LoadComments(jamID, gameID string) ([]fetcher.Comment, error) {
+	var comments []fetcher.Comment
+	err := d.view(func(tx *sql.Tx) error {
+		var found int
+		err := tx.QueryRow("SELECT 1 FROM submissions WHERE jam_id = ? AND game_id = ?", jamID, gameID).Scan(&found)
+		if errors.Is(err, sql.ErrNoRows) {
+			return notStored("submission %s of jam %s is not in the database", gameID, jamID)
+		}
+		if err != nil {
+			return err
+		}
+
+		comments, err = loadComments(tx, jamID, gameID)
+		return err
+	})
+	return comments, err
+}
+
+// loadSubmission loads a submission from the rows of all its tables
+func loadSubmission(tx *sql.Tx, jamID, gameID string) (*fetcher.GameSubmission, error) {
+	game := &fetcher.GameSubmission{ID: gameID}
+	var resultGameID sql.NullInt64
+	err := tx.QueryRow(`SELECT result_game_id, title, url, description, created_at, coolness, rating_count,
+			cover_url, cover_color, status, genre, average_session, license, media_dir
+		FROM submissions WHERE jam_id = ? AND game_id = ?`, jamID, gameID).
+		Scan(&resultGameID, &game.Title, &game.URL, &game.Description, &game.CreatedAt, &game.Coolness,
+			&game.RatingCount, &game.Cover.URL, &game.Cover.Color, &game.Status, &game.Genre,
+			&game.AverageSession, &game.License, &game.MediaDir)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, notStored("submission %s of jam %s is not in the database", gameID, jamID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if resultGameID.Valid {
+		if game.Results, err = loadEntryResult(tx, jamID, int(resultGameID.Int64)); err != nil {
+			return nil, err
+		}
+	}
+
+	err = queryRows(tx, func(rows *sql.Rows) error {
+		var author fetcher.User
+		if err := rows.Scan(&author.ID, &author.Name, &author.URL); err != nil {
+			return err
+		}
+		game.Authors = append(game.Authors, author)
+		return nil
+	}, "SELECT user_id, name, url FROM submission_authors WHERE jam_id = ? AND game_id = ? ORDER BY position", jamID, gameID)
+	if err != nil {
+		return nil, err
+	}
+
+	lists := submissionLists(game)
+	err = queryRows(tx, func(rows *sql.Rows) error {
+		var field, value string
+		if err := rows.Scan(&field, &value); err != nil {
+			return err
+		}
+		if values, ok := lists[field]; ok {
+			*values = append(*values, value)
+		}
+		return nil
+	}, "SELECT field, value FROM submission_values WHERE jam_id = ? AND game_id = ? ORDER BY field, position", jamID, gameID)
+	if err != nil {
+		return nil, err
+	}
+
+	maps := submissionMaps(game)
+	err = queryRows(tx, func(rows *sql.Rows) error {
+		var field, key, value string
+		if err := rows.Scan(&field, &key, &value); err != nil {
+			return err
+		}
+		if values, ok := maps[field]; ok {
+			if *values == nil {
+				*values = make(map[string]string)
+			}
+			(*values)[key] = value
+		}
+		return nil
+	}, "SELECT field, key, value FROM submission_fields WHERE jam_id = ? AND game_id = ?", jamID, gameID)
+	if err != nil {
+		return nil, err
+	}
+
+	if game.Downloads, err = loadDownloads(tx, jamID, gameID); err != nil {
+		return nil, err
+	}
+	if game.Comments, err = loadComments(tx, jamID, gameID); err != nil {
+		return nil, err
+	}
+	return game, nil
+}
+
+// loadEntryResult loads the result of one game of a jam
+func loadEntryResult(tx *sql.Tx, jamID string, gameID int) (*fetcher.EntryResult, error) {
+	entry := &fetcher.EntryResult{GameID: gameID}
+	err := tx.QueryRow(`SELECT rate_url, title, rank, score, raw_score, rating_count
+		FROM results WHERE jam_id = ? AND game_id = ?`, jamID, gameID).
+		Scan(&entry.RateURL, &entry.Title, &entry.Rank, &entry.Score, &entry.RawScore, &entry.RatingCount)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	err = queryRows(tx, func(rows *sql.Rows) error {
+		var criterion fetcher.CriterionResult
+		if err := rows.Scan(&criterion.Name, &criterion.Rank, &criterion.Score, &criterion.RawScore); err != nil {
+			return err
+		}
+		entry.Criteria = append(entry.Criteria, criterion)
+		return nil
+	}, `SELECT name, rank, score, raw_score FROM result_criteria
+		WHERE jam_id = ? AND game_id = ? ORDER BY position`, jamID, gameID)
+	if err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// loadDownloads loads the downloads of a submission with their platforms
+func loadDownloads(tx *sql.Tx, jamID, gameID string) ([]fetcher.Download, error) {
+	var downloads []fetcher.Download
+	err := queryRows(tx, func(rows *sql.Rows) error {
+		var download fetcher.Download
+		if err := rows.Scan(&download.Filename, &download.Size, &download.UploadDate, &download.UploadID,
+			&download.URL, &download.Path, &download.Bytes, &download.SHA256); err != nil {
+			return err
+		}
+		downloads = append(downloads, download)
+		return nil
+	}, `SELECT filename, size, upload_date, upload_id, url, path, bytes, sha256
+		FROM downloads WHERE jam_id = ? AND game_id = ? ORDER BY position`, jamID, gameID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = queryRows(tx, func(rows *sql.Rows) error {
+		var download int
+		var platform string
+		if err := rows.Scan(&download, &platform); err != nil {
+			return err
+		}
+		if download < len(downloads) {
+			downloads[download].Platforms = append(downloads[download].Platforms, platform)
+		}
+		return nil
+	}, `SELECT download, platform FROM download_platforms
+		WHERE jam_id = ? AND game_id = ? ORDER BY download, position`, jamID, gameID)
+	if err != nil {
+		return nil, err
+	}
+	return downloads, nil
+}
+
+// loadComments loads the comments of a submission with their ratings
+func loadComments(tx *sql.Tx, jamID, gameID string) ([]fetcher.Comment, error) {
+	var comments []fetcher.Comment
+	err := queryRows(tx, func(rows *sql.Rows) error {
+		var comment fetcher.Comment
+		if err := rows.Scan(&comment.ID, &comment.ParentID, &comment.Author, &comment.AuthorURL,
+			&comment.IsDeveloper, &comment.Content, &comment.Timestamp); err != nil {
+			return err
+		}
+		comments = append(comments, comment)
+		return nil
+	}, `SELECT comment_id, parent_id, author, author_url, is_developer, content, timestamp
+		FROM comments WHERE jam_id = ? AND game_id = ? ORDER BY position`, jamID, gameID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = queryRows(tx, func(rows *sql.Rows) error {
+		var comment, stars int
+		var criterion string
+		if err := rows.Scan(&comment, &criterion, &stars); err != nil {
+			return err
+		}
+		if comment < len(comments) {
+			if comments[comment].Ratings == nil {
+				comments[comment].Ratings = make(map[string]int)
+			}
+			comments[comment].Ratings[criterion] = stars
+		}
+		return nil
+	}, "SELECT comment, criterion, stars FROM comment_ratings WHERE jam_id = ? AND game_id = ?", jamID, gameID)
+	if err != nil {
+		return nil, err
+	}
+	return comments, nil
+}
@@ -0,0 +1,143 @@
+package storage
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+
+	"Itchalyser/fetcher"
+)
+
+// Export formats
+const (
+	ExportCSV   = "csv"
+	ExportJSONL = "jsonl"
+	ExportJSON  = "json"
+)
+
+// ExportFormats lists the supported export formats
+var ExportFormats = []string{ExportCSV, ExportJSONL, ExportJSON}
+
+// csvHeader names the columns of a CSV export, one row per submission
+var csvHeader = []string{
+	"jam_id", "game_id", "title", "url", "authors", "platforms", "created_at",
+	"coolness", "rating_count", "rank", "score", "raw_score", "status", "genre",
+	"tags", "made_with", "languages", "screenshots", "downloads", "comments",
+}
+
+// WriteCSV writes the submissions of jams as CSV, one row per submission. List fields
+// such as tags are joined with semicolons. Submissions are streamed from disk.
+func WriteCSV(w io.Writer, jams []*Jam) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(csvHeader); err != nil {
+		return err
+	}
+
+	for _, jam := range jams {
+		for game, err := range jam.Submissions() {
+			if err != nil {
+				return err
+			}
+
+			authors := make([]string, 0, len(game.Authors))
+			for _, author := range game.Authors {
+				authors = append(authors, author.Name)
+			}
+
+			var rank, score, rawScore string
+			if game.Results != nil && game.Results.Rank > 0 {
+				rank = strconv.Itoa(game.Results.Rank)
+				score = strconv.FormatFloat(game.Results.Score, 'f', 3, 64)
+				rawScore = strconv.FormatFloat(game.Results.RawScore, 'f', 3, 64)
+			}
+
+			record := []string{
+				jam.ID, game.ID, game.Title, game.URL,
+				strings.Join(authors, ";"),
+				strings.Join(game.Platforms, ";"),
+				game.CreatedAt,
+				strconv.Itoa(game.Coolness),
+				strconv.Itoa(game.RatingCount),
+				rank, score, rawScore,
+				game.Status, game.Genre,
+				strings.Join(game.Tags, ";"),
+				strings.Join(game.MadeWith, ";"),
+				strings.Join(game.Languages, ";"),
+				strconv.Itoa(len(game.Screenshots)),
+				strconv.Itoa(len(game.Downloads)),
+				strconv.Itoa(len(game.Comments)),
+			}
+			if err := writer.Write(record); err != nil {
+				return err
+			}
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// exportedSubmission is a submission in a JSON or JSON Lines export, tagged with its jam
+type exportedSubmission struct {
+	JamID string `json:"jam_id"`
+	*fetcher.GameSubmission
+}
+
+// WriteJSONL writes the submissions of jams as JSON Lines, one submission per line.
+// Submissions are streamed from disk.
+func WriteJSONL(w io.Writer, jams []*Jam) error {
+	encoder := json.NewEncoder(w)
+	for _, jam := range jams {
+		for game, err := range jam.Submissions() {
+			if err != nil {
+				return err
+			}
+			if err := encoder.Encode(exportedSubmission{JamID: jam.ID, GameSubmission: game}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// WriteJSON writes the submissions of jams as one indented JSON array. Submissions are
+// streamed from disk and written one element at a time.
+func WriteJSON(w io.Writer, jams []*Jam) error {
+	if _, err := io.WriteString(w, "["); err != nil {
+		return err
+	}
+
+	first := true
+	for _, jam := range jams {
+		for game, err := range jam.Submissions() {
+			if err != nil {
+				return err
+			}
+
+			data, err := json.MarshalIndent(exportedSubmission{JamID: jam.ID, GameSubmission: game}, "  ", "  ")
+			if err != nil {
+				return err
+			}
+			separator := ",\n  "
+			if first {
+				separator = "\n  "
+				first = false
+			}
+			if _, err := io.WriteString(w, separator); err != nil {
+				return err
+			}
+			if _, err := w.Write(data); err != nil {
+				return err
+			}
+		}
+	}
+
+	closing := "\n]\n"
+	if first {
+		closing = "]\n"
+	}
+	_, err := io.WriteString(w, closing)
+	return err
+}
@@ -0,0 +1,117 @@
+package storage
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"io"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"Itchalyser/fetcher"
+	"Itchalyser/fsutil"
+)
+
+// htmlReport is the template of a jam's HTML report
+var htmlReport = template.Must(template.New("report").Funcs(template.FuncMap{
+	"join":  strings.Join,
+	"media": func(base, rel string) string { return base + rel },
+	"cover": coverPath,
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Metadata.Title}}</title>
+<style>
+body { font-family: sans-serif; max-width: 960px; margin: 2em auto; padding: 0 1em; color: #222; }
+table { border-collapse: collapse; width: 100%; }
+th, td { border-bottom: 1px solid #ddd; padding: 4px 8px; text-align: left; }
+td.num, th.num { text-align: right; }
+.game { border-top: 1px solid #ccc; padding: 1em 0; overflow: hidden; }
+.game img.cover { float: right; max-width: 240px; margin-left: 1em; }
+.description { white-space: pre-wrap; }
+.meta { color: #666; }
+</style>
+</head>
+<body>
+<h1>{{.Metadata.Title}}</h1>
+<ul class="meta">
+<li>ID: {{.Metadata.ID}}</li>
+{{with .Metadata.Theme}}<li>Theme: {{.}}</li>{{end}}
+{{with .Metadata.Hosts}}<li>Hosts: {{range $i, $host := .}}{{if $i}}, {{end}}<a href="{{$host.URL}}">{{$host.Name}}</a>{{end}}</li>{{end}}
+{{with .Metadata.StartDate}}<li>Start: {{.}}</li>{{end}}
+{{with .Metadata.EndDate}}<li>End: {{.}}</li>{{end}}
+<li>Submissions: {{len .Submissions}}</li>
+</ul>
+{{if .Ranked}}
+<h2>Leaderboard</h2>
+<table>
+<tr><th class="num">Rank</th><th>Game</th><th class="num">Score</th><th class="num">Raw Score</th><th class="num">Ratings</th></tr>
+{{range .Ranked}}<tr><td class="num">#{{.Results.Rank}}</td><td><a href="{{.URL}}">{{.Title}}</a></td><td class="num">{{printf "%.3f" .Results.Score}}</td><td class="num">{{printf "%.3f" .Results.RawScore}}</td><td class="num">{{.Results.RatingCount}}</td></tr>
+{{end}}</table>
+{{end}}
+<h2>Game Submissions</h2>
+{{range .Submissions}}<div class="game" id="game-{{.ID}}">
+{{with cover .}}<img class="cover" src="{{media $.MediaBase .}}" alt="">{{end}}
+<h3><a href="{{.URL}}">{{.Title}}</a></h3>
+<ul class="meta">
+{{with .Authors}}<li>Authors: {{range $i, $author := .}}{{if $i}}, {{end}}<a href="{{$author.URL}}">{{$author.Name}}</a>{{end}}</li>{{end}}
+{{with .Platforms}}<li>Platforms: {{join . ", "}}</li>{{end}}
+{{with .Genre}}<li>Genre: {{.}}</li>{{end}}
+{{with .Tags}}<li>Tags: {{join . ", "}}</li>{{end}}
+{{with .MadeWith}}<li>Made with: {{join . ", "}}</li>{{end}}
+<li>Ratings: {{.RatingCount}}</li>
+{{if and .Results .Results.Rank}}<li>Rank: #{{.Results.Rank}} (Score: {{printf "%.3f" .Results.Score}})</li>{{end}}
+</ul>
+{{with .Description}}<div class="description">{{.}}</div>{{end}}
+{{with .Downloads}}<p>Downloads:</p>
+<ul>{{range .}}<li>{{if .Path}}<a href="{{media $.MediaBase .Path}}">{{.Filename}}</a>{{else}}{{.Filename}}{{end}}{{with .Size}} ({{.}}){{end}}{{with .Platforms}} - For {{join . ", "}}{{end}}</li>
+{{end}}</ul>{{end}}
+</div>
+{{end}}
+</body>
+</html>
+`))
+
+// WriteHTMLReport writes an HTML report of a jam to w. Covers and downloaded files are
+// linked by their path in the output directory, prefixed with mediaBase.
+func WriteHTMLReport(w io.Writer, jam *JamArchive, mediaBase string) error {
+	var ranked []*fetcher.GameSubmission
+	for _, game := range jam.Submissions {
+		if game.Results != nil && game.Results.Rank > 0 {
+			ranked = append(ranked, game)
+		}
+	}
+
+	return htmlReport.Execute(w, struct {
+		*JamArchive
+		Ranked    []*fetcher.GameSubmission
+		MediaBase string
+	}{jam, ranked, mediaBase})
+}
+
+// GenerateHTMLReport generates an HTML report for a jam next to its markdown report
+func (m *Manager) GenerateHTMLReport(jam *JamArchive) error {
+	reportDir := filepath.Join(m.baseDir, "reports")
+	if err := m.CreateDirectory(reportDir); err != nil {
+		return err
+	}
+
+	// Reports sit one directory below the output directory
+	report := &bytes.Buffer{}
+	if err := WriteHTMLReport(report, jam, "../"); err != nil {
+		return err
+	}
+
+	return fsutil.WriteFile(filepath.Join(reportDir, fmt.Sprintf("%s-report.html", jam.ID)), report.Bytes())
+}
+
+// coverPath returns the path of a game's downloaded cover relative to the output
+// directory, or an empty string if its media was not downloaded
+func coverPath(game *fetcher.GameSubmission) string {
+	if game.MediaDir == "" || game.Cover.URL == "" {
+		return ""
+	}
+	return path.Join(game.MediaDir, "cover"+filepath.Ext(game.Cover.URL))
+}
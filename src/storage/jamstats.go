@@ -0,0 +1,129 @@
+package storage
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+)
+
+// JamStats summarises the stored data of a jam
+type JamStats struct {
+	JamID       string  `json:"jam_id"`
+	Title       string  `json:"title"`
+	Submissions int     `json:"submissions"`
+	Ranked      int     `json:"ranked"`   // Submissions with a final rank
+	Ratings     int     `json:"ratings"`  // Ratings received by all submissions
+	Comments    int     `json:"comments"` // Comments and replies on all submissions
+	Screenshots int     `json:"screenshots"`
+	MediaFiles  int     `json:"media_files"` // Media files listed in the games' manifests
+	MediaBytes  int64   `json:"media_bytes"`
+	GameFiles   int     `json:"game_files"` // Downloaded game files
+	GameBytes   int64   `json:"game_bytes"`
+	Platforms   []Count `json:"platforms"`
+	Engines     []Count `json:"engines"` // From the games' "Made with"
+	Genres      []Count `json:"genres"`
+	Tags        []Count `json:"tags"`
+}
+
+// Count is the number of submissions with a certain value, such as a platform or tag
+type Count struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+// Stats computes the statistics of the jam, streaming its submissions and reading the
+// media manifests of its games
+func (j *Jam) Stats() (*JamStats, error) {
+	stats := &JamStats{
+		JamID: j.ID,
+		Title: j.Metadata.Title,
+	}
+
+	platforms := newCounter()
+	engines := newCounter()
+	genres := newCounter()
+	tags := newCounter()
+
+	for game, err := range j.Submissions() {
+		if err != nil {
+			return nil, err
+		}
+
+		stats.Submissions++
+		if game.Results != nil && game.Results.Rank > 0 {
+			stats.Ranked++
+		}
+		stats.Ratings += game.RatingCount
+		stats.Comments += len(game.Comments)
+		stats.Screenshots += len(game.Screenshots)
+
+		platforms.add(game.Platforms)
+		engines.add(game.MadeWith)
+		tags.add(game.Tags)
+		genres.add([]string{game.Genre})
+
+		if game.MediaDir != "" {
+			if manifest, err := j.backend.LoadMediaManifest(game.ID); err == nil {
+				stats.MediaFiles += len(manifest.Files)
+				for _, file := range manifest.Files {
+					stats.MediaBytes += file.Size
+				}
+			}
+		}
+		for _, download := range game.Downloads {
+			if download.Path != "" {
+				stats.GameFiles++
+				stats.GameBytes += download.Bytes
+			}
+		}
+	}
+
+	stats.Platforms = platforms.sorted()
+	stats.Engines = engines.sorted()
+	stats.Genres = genres.sorted()
+	stats.Tags = tags.sorted()
+	return stats, nil
+}
+
+// counter counts how many submissions have each value of a field, ignoring case
+type counter struct {
+	counts map[string]*Count // By lower-case value; names keep the first spelling seen
+}
+
+// newCounter creates an empty counter
+func newCounter() *counter {
+	return &counter{counts: make(map[string]*Count)}
+}
+
+// add counts each distinct value of one submission once
+func (c *counter) add(values []string) {
+	seen := make(map[string]bool)
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		key := strings.ToLower(value)
+		if key == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		if count, ok := c.counts[key]; ok {
+			count.Count++
+		} else {
+			c.counts[key] = &Count{Name: value, Count: 1}
+		}
+	}
+}
+
+// sorted returns the counts ordered by count, most common first
+func (c *counter) sorted() []Count {
+	list := make([]Count, 0, len(c.counts))
+	for _, count := range c.counts {
+		list = append(list, *count)
+	}
+	slices.SortFunc(list, func(a, b Count) int {
+		if c := cmp.Compare(b.Count, a.Count); c != 0 {
+			return c
+		}
+		return strings.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name))
+	})
+	return list
+}
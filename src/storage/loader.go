@@ -0,0 +1,360 @@
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"iter"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"Itchalyser/fetcher"
+)
+
+// JamArchive is a scraped jam loaded back from the output directory with all its
+// submissions in memory
+type JamArchive struct {
+	ID          string
+	Metadata    *fetcher.JamMetadata
+	Results     *fetcher.JamResults       // Nil when no results were saved
+	Submissions []*fetcher.GameSubmission // Ranked games by rank first, then the rest by title
+}
+
+// ListJams returns the IDs of the jams stored in the output directory, in sorted order
+func (m *Manager) ListJams() ([]string, error) {
+	entries, err := os.ReadDir(filepath.Join(m.baseDir, "jams"))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var jamIDs []string
+	for _, entry := range entries {
+		if entry.IsDir() && fileExists(filepath.Join(m.baseDir, "jams", entry.Name(), "meta.json")) {
+			jamIDs = append(jamIDs, entry.Name())
+		}
+	}
+	return jamIDs, nil
+}
+
+// LoadJamMetadata loads the saved metadata of a jam
+func (m *Manager) LoadJamMetadata(jamID string) (*fetcher.JamMetadata, error) {
+	var metadata fetcher.JamMetadata
+	if err := loadJSONFromFile(filepath.Join(m.baseDir, "jams", jamID, "meta.json"), &metadata); err != nil {
+		return nil, err
+	}
+	return &metadata, nil
+}
+
+// LoadJamResults loads the saved results of a jam, returning nil if it has none
+func (m *Manager) LoadJamResults(jamID string) (*fetcher.JamResults, error) {
+	var results fetcher.JamResults
+	err := loadJSONFromFile(filepath.Join(m.baseDir, "jams", jamID, "results.json"), &results)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &results, nil
+}
+
+// Jam is a stored jam opened for reading. Its metadata and results are loaded when it
+// is opened; its submissions are only read from the backend while they are iterated,
+// so a jam with many entries never has to be held in memory.
+type Jam struct {
+	ID       string
+	Metadata *fetcher.JamMetadata
+	Results  *fetcher.JamResults // Nil when no results were saved
+
+	backend Backend
+}
+
+// OpenJam opens a jam stored in a backend for reading
+func OpenJam(backend Backend, jamID string) (*Jam, error) {
+	metadata, err := backend.LoadJamMetadata(jamID)
+	if err != nil {
+		return nil, err
+	}
+
+	results, err := backend.LoadJamResults(jamID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Jam{ID: jamID, Metadata: metadata, Results: results, backend: backend}, nil
+}
+
+// OpenJams opens the given jams, or every jam stored in the backend if none are given
+func OpenJams(backend Backend, jamIDs []string) ([]*Jam, error) {
+	if len(jamIDs) == 0 {
+		var err error
+		if jamIDs, err = backend.ListJams(); err != nil {
+			return nil, err
+		}
+	}
+
+	jams := make([]*Jam, 0, len(jamIDs))
+	for _, jamID := range jamIDs {
+		jam, err := OpenJam(backend, jamID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open jam %s: %w", jamID, err)
+		}
+		jams = append(jams, jam)
+	}
+	return jams, nil
+}
+
+// ListSubmissions returns the game IDs of the submissions stored for a jam, in numeric order
+func (m *Manager) ListSubmissions(jamID string) ([]string, error) {
+	gameIDs, err := m.submissionFileIDs(jamID)
+	if err != nil || gameIDs != nil {
+		return gameIDs, err
+	}
+
+	lines, err := m.jsonlLastLines(jamID)
+	if err != nil {
+		return nil, err
+	}
+	for gameID := range lines {
+		gameIDs = append(gameIDs, gameID)
+	}
+	slices.SortFunc(gameIDs, compareGameIDs)
+	return gameIDs, nil
+}
+
+// Submissions iterates over the submissions of a jam, reading one at a time. They come
+// from their game.json files in game ID order, or from submissions.jsonl in file order
+// when the jam was scraped without the json output; a game appearing twice in the JSON
+// Lines file is read from its last line. A submission that cannot be read yields an
+// error, and iteration goes on with the next one.
+func (m *Manager) Submissions(jamID string) iter.Seq2[*fetcher.GameSubmission, error] {
+	return func(yield func(*fetcher.GameSubmission, error) bool) {
+		gameIDs, err := m.submissionFileIDs(jamID)
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+
+		if gameIDs != nil {
+			for _, gameID := range gameIDs {
+				var game fetcher.GameSubmission
+				if err := loadJSONFromFile(m.submissionPath(jamID, gameID), &game); err != nil {
+					if !yield(nil, err) {
+						return
+					}
+					continue
+				}
+				if !yield(&game, nil) {
+					return
+				}
+			}
+			return
+		}
+
+		m.jsonlSubmissions(jamID, yield)
+	}
+}
+
+// Submissions iterates over the jam's submissions, see Backend.Submissions
+func (j *Jam) Submissions() iter.Seq2[*fetcher.GameSubmission, error] {
+	return j.backend.Submissions(j.ID)
+}
+
+// Load reads all submissions of the jam into memory, ranked games first. It fails on
+// the first submission that cannot be read.
+func (j *Jam) Load() (*JamArchive, error) {
+	var submissions []*fetcher.GameSubmission
+	for game, err := range j.Submissions() {
+		if err != nil {
+			return nil, err
+		}
+		submissions = append(submissions, game)
+	}
+
+	slices.SortStableFunc(submissions, compareSubmissions)
+
+	return &JamArchive{
+		ID:          j.ID,
+		Metadata:    j.Metadata,
+		Results:     j.Results,
+		Submissions: submissions,
+	}, nil
+}
+
+// LoadJam loads a jam stored in a backend with all its submissions, see Jam.Load
+func LoadJam(backend Backend, jamID string) (*JamArchive, error) {
+	jam, err := OpenJam(backend, jamID)
+	if err != nil {
+		return nil, err
+	}
+	return jam.Load()
+}
+
+// LoadComments loads the comments of a stored submission, which on disk means loading
+// all of its game.json
+func (m *Manager) LoadComments(jamID, gameID string) ([]fetcher.Comment, error) {
+	game, err := m.LoadGameSubmission(jamID, gameID)
+	if err != nil {
+		return nil, err
+	}
+	return game.Comments, nil
+}
+
+// Close does nothing; files are closed as soon as they are read or written
+func (m *Manager) Close() error {
+	return nil
+}
+
+// submissionPath returns the location of a submission's game.json
+func (m *Manager) submissionPath(jamID, gameID string) string {
+	return filepath.Join(m.baseDir, "jams", jamID, "submissions", gameID, "game.json")
+}
+
+// submissionFileIDs returns the game IDs with a game.json in a jam, in numeric order,
+// or nil if there are none
+func (m *Manager) submissionFileIDs(jamID string) ([]string, error) {
+	entries, err := os.ReadDir(filepath.Join(m.baseDir, "jams", jamID, "submissions"))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var gameIDs []string
+	for _, entry := range entries {
+		if entry.IsDir() && fileExists(m.submissionPath(jamID, entry.Name())) {
+			gameIDs = append(gameIDs, entry.Name())
+		}
+	}
+	slices.SortFunc(gameIDs, compareGameIDs)
+	return gameIDs, nil
+}
+
+// jsonlLine holds the only field read from a JSON Lines submission while indexing the file
+type jsonlLine struct {
+	ID string `json:"id"`
+}
+
+// scanJSONL calls fn with each non-empty line of a jam's JSON Lines file and its line
+// number. A missing file has no lines.
+func (m *Manager) scanJSONL(jamID string, fn func(line int, data []byte) bool) error {
+	file, err := os.Open(m.SubmissionsJSONLPath(jamID))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, 64<<20) // Descriptions and comments make for long lines
+	for line := 1; scanner.Scan(); line++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		if !fn(line, scanner.Bytes()) {
+			return nil
+		}
+	}
+	return scanner.Err()
+}
+
+// jsonlLastLines indexes a jam's JSON Lines file by game ID, mapping each game to the
+// last line it appears on
+func (m *Manager) jsonlLastLines(jamID string) (map[string]int, error) {
+	lines := make(map[string]int)
+	err := m.scanJSONL(jamID, func(line int, data []byte) bool {
+		var entry jsonlLine
+		if json.Unmarshal(data, &entry) == nil {
+			lines[entry.ID] = line
+		}
+		return true
+	})
+	return lines, err
+}
+
+// jsonlSubmissions yields the submissions of a jam's JSON Lines file, skipping lines
+// replaced by a later line of the same game
+func (m *Manager) jsonlSubmissions(jamID string, yield func(*fetcher.GameSubmission, error) bool) {
+	path := m.SubmissionsJSONLPath(jamID)
+
+	lines, err := m.jsonlLastLines(jamID)
+	if err != nil {
+		yield(nil, err)
+		return
+	}
+
+	err = m.scanJSONL(jamID, func(line int, data []byte) bool {
+		var game fetcher.GameSubmission
+		if err := json.Unmarshal(data, &game); err != nil {
+			return yield(nil, fmt.Errorf("%s:%d: %w", path, line, err))
+		}
+		if lines[game.ID] != line {
+			return true
+		}
+		return yield(&game, nil)
+	})
+	if err != nil {
+		yield(nil, err)
+	}
+}
+
+// compareGameIDs orders numeric game IDs by value and anything else after them by text
+func compareGameIDs(a, b string) int {
+	x, errA := strconv.Atoi(a)
+	y, errB := strconv.Atoi(b)
+	switch {
+	case errA == nil && errB == nil:
+		return cmp.Compare(x, y)
+	case errA == nil:
+		return -1
+	case errB == nil:
+		return 1
+	}
+	return strings.Compare(a, b)
+}
+
+// compareSubmissions orders ranked games by rank before unranked ones, and games of
+// equal rank by title
+func compareSubmissions(a, b *fetcher.GameSubmission) int {
+	rank := func(game *fetcher.GameSubmission) int {
+		if game.Results == nil || game.Results.Rank == 0 {
+			return math.MaxInt
+		}
+		return game.Results.Rank
+	}
+	if c := cmp.Compare(rank(a), rank(b)); c != 0 {
+		return c
+	}
+	return cmp.Compare(strings.ToLower(a.Title), strings.ToLower(b.Title))
+}
+
+// loadJSONFromFile loads JSON from a file into obj, naming the file in parse errors
+func loadJSONFromFile(path string, obj any) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, obj); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+// fileExists reports whether a regular file exists at path
+func fileExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.Mode().IsRegular()
+}
@@ -0,0 +1,211 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+// writeFile writes a file of a test archive, creating its directory
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSubmissions(t *testing.T) {
+	const jamID = "fixture-jam"
+
+	tests := []struct {
+		name       string
+		gameFiles  map[string]string // game.json contents by game ID
+		jsonl      string
+		wantListed []string
+		wantGames  []string // ID and title of each yielded submission, in order
+		wantErrors int
+	}{
+		{
+			name:       "no submissions",
+			wantListed: nil,
+			wantGames:  nil,
+		},
+		{
+			name: "game.json files in numeric order",
+			gameFiles: map[string]string{
+				"10": `{"id":"10","title":"Ten"}`,
+				"2":  `{"id":"2","title":"Two"}`,
+				"1":  `{"id":"1","title":"One"}`,
+			},
+			wantListed: []string{"1", "2", "10"},
+			wantGames:  []string{"1 One", "2 Two", "10 Ten"},
+		},
+		{
+			name: "unreadable game.json is an error and iteration goes on",
+			gameFiles: map[string]string{
+				"1": `{"id":"1","title":"One"}`,
+				"2": `{"id":"2",`,
+				"3": `{"id":"3","title":"Three"}`,
+			},
+			wantListed: []string{"1", "2", "3"},
+			wantGames:  []string{"1 One", "3 Three"},
+			wantErrors: 1,
+		},
+		{
+			name: "game.json files win over submissions.jsonl",
+			gameFiles: map[string]string{
+				"1": `{"id":"1","title":"One"}`,
+			},
+			jsonl:      `{"id":"2","title":"Two"}` + "\n",
+			wantListed: []string{"1"},
+			wantGames:  []string{"1 One"},
+		},
+		{
+			name: "submissions.jsonl fallback keeps the last line of a game",
+			jsonl: `{"id":"1","title":"Old"}` + "\n" +
+				`{"id":"2","title":"Two"}` + "\n\n" +
+				`{"id":"1","title":"New"}` + "\n",
+			wantListed: []string{"1", "2"},
+			wantGames:  []string{"2 Two", "1 New"},
+		},
+		{
+			name: "unreadable submissions.jsonl line is an error and iteration goes on",
+			jsonl: `{"id":"1","title":"One"}` + "\n" +
+				`not json` + "\n" +
+				`{"id":"2","title":"Two"}` + "\n",
+			wantListed: []string{"1", "2"},
+			wantGames:  []string{"1 One", "2 Two"},
+			wantErrors: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewManager(t.TempDir())
+			for gameID, content := range tt.gameFiles {
+				writeFile(t, m.submissionPath(jamID, gameID), content)
+			}
+			if tt.jsonl != "" {
+				writeFile(t, m.SubmissionsJSONLPath(jamID), tt.jsonl)
+			}
+
+			listed, err := m.ListSubmissions(jamID)
+			if err != nil {
+				t.Fatalf("ListSubmissions: %v", err)
+			}
+			if !reflect.DeepEqual(listed, tt.wantListed) {
+				t.Errorf("ListSubmissions = %v, want %v", listed, tt.wantListed)
+			}
+
+			var games []string
+			errorCount := 0
+			for game, err := range m.Submissions(jamID) {
+				if err != nil {
+					errorCount++
+					continue
+				}
+				games = append(games, game.ID+" "+game.Title)
+			}
+			if !reflect.DeepEqual(games, tt.wantGames) {
+				t.Errorf("Submissions = %v, want %v", games, tt.wantGames)
+			}
+			if errorCount != tt.wantErrors {
+				t.Errorf("Submissions errors = %d, want %d", errorCount, tt.wantErrors)
+			}
+		})
+	}
+}
+
+func TestSubmissionsStopsWhenAsked(t *testing.T) {
+	m := NewManager(t.TempDir())
+	writeFile(t, m.SubmissionsJSONLPath("fixture-jam"), `{"id":"1"}`+"\n"+`{"id":"2"}`+"\n")
+
+	count := 0
+	for range m.Submissions("fixture-jam") {
+		count++
+		break
+	}
+	if count != 1 {
+		t.Errorf("submissions read after break = %d, want 1", count)
+	}
+}
+
+func TestOpenJams(t *testing.T) {
+	m := NewManager(t.TempDir())
+	jamDir := func(jamID string) string { return filepath.Join(m.baseDir, "jams", jamID) }
+	writeFile(t, filepath.Join(jamDir("alpha-jam"), "meta.json"), `{"id":"alpha-jam","title":"Alpha Jam"}`)
+	writeFile(t, filepath.Join(jamDir("alpha-jam"), "results.json"), `{"jam_id":"alpha-jam","criteria":["Overall"]}`)
+	writeFile(t, filepath.Join(jamDir("beta-jam"), "meta.json"), `{"id":"beta-jam","title":"Beta Jam"}`)
+	writeFile(t, filepath.Join(jamDir("broken-jam"), "meta.json"), `{"id":"broken-jam"}`)
+	writeFile(t, filepath.Join(jamDir("broken-jam"), "results.json"), `{`)
+	// A directory without meta.json is not a jam
+	writeFile(t, filepath.Join(jamDir("stray"), "state.json"), `{}`)
+
+	tests := []struct {
+		name    string
+		jamIDs  []string
+		want    []string // ID, title and whether the jam has results
+		wantErr bool
+	}{
+		{
+			name:    "every stored jam",
+			wantErr: true, // broken-jam is listed and cannot be opened
+		},
+		{
+			name:   "given jams",
+			jamIDs: []string{"beta-jam", "alpha-jam"},
+			want:   []string{"beta-jam Beta Jam false", "alpha-jam Alpha Jam true"},
+		},
+		{
+			name:    "missing jam",
+			jamIDs:  []string{"alpha-jam", "missing-jam"},
+			wantErr: true,
+		},
+		{
+			name:    "unreadable results",
+			jamIDs:  []string{"broken-jam"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			jams, err := OpenJams(m, tt.jamIDs)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("OpenJams error = %v, want error %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			var got []string
+			for _, jam := range jams {
+				got = append(got, jam.ID+" "+jam.Metadata.Title+" "+strconv.FormatBool(jam.Results != nil))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("OpenJams = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// Without the broken jam, every stored jam is opened in sorted order
+	if err := os.RemoveAll(jamDir("broken-jam")); err != nil {
+		t.Fatal(err)
+	}
+	jams, err := OpenJams(m, nil)
+	if err != nil {
+		t.Fatalf("OpenJams: %v", err)
+	}
+	var got []string
+	for _, jam := range jams {
+		got = append(got, jam.ID)
+	}
+	if want := []string{"alpha-jam", "beta-jam"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("OpenJams = %v, want %v", got, want)
+	}
+}
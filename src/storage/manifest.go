@@ -0,0 +1,155 @@
+package storage
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"Itchalyser/fetcher"
+)
+
+// MediaManifest lists the media files downloaded for a game with their checksums
+type MediaManifest struct {
+	Files []MediaFile `json:"files"`
+}
+
+// MediaFile describes one downloaded media file
+type MediaFile struct {
+	Name         string    `json:"name"` // File name inside the media directory
+	URL          string    `json:"url"`
+	Size         int64     `json:"size"`
+	SHA256       string    `json:"sha256"`
+	DownloadedAt time.Time `json:"downloaded_at"`
+}
+
+// Put records a downloaded file, replacing the entry of an earlier download of the same name
+func (m *MediaManifest) Put(file MediaFile) {
+	for i := range m.Files {
+		if m.Files[i].Name == file.Name {
+			m.Files[i] = file
+			return
+		}
+	}
+	m.Files = append(m.Files, file)
+}
+
+// MediaProblem describes a media file that does not match its manifest entry
+type MediaProblem struct {
+	Name    string `json:"name"`
+	Problem string `json:"problem"`
+}
+
+// GameDir returns the directory holding a game's jam-independent data. A game entered
+// in several jams has its media and files stored here once, shared by all its submissions.
+func (m *Manager) GameDir(gameID string) string {
+	return filepath.Join(m.baseDir, "games", gameID)
+}
+
+// MediaDir returns the directory holding a game's media files and manifest
+func (m *Manager) MediaDir(gameID string) string {
+	return filepath.Join(m.GameDir(gameID), "media")
+}
+
+// FilesDir returns the directory holding a game's downloaded files
+func (m *Manager) FilesDir(gameID string) string {
+	return filepath.Join(m.GameDir(gameID), "files")
+}
+
+// RelPath returns path relative to the output directory, with forward slashes, for
+// recording in output files
+func (m *Manager) RelPath(path string) string {
+	rel, err := filepath.Rel(m.baseDir, path)
+	if err != nil {
+		return filepath.ToSlash(path)
+	}
+	return filepath.ToSlash(rel)
+}
+
+// AbsPath turns a path recorded in an output file by RelPath back into a local path
+func (m *Manager) AbsPath(rel string) string {
+	return filepath.Join(m.baseDir, filepath.FromSlash(rel))
+}
+
+// SaveMediaManifest saves the media manifest of a game
+func (m *Manager) SaveMediaManifest(gameID string, manifest *MediaManifest) error {
+	return m.saveJSONToFile(filepath.Join(m.MediaDir(gameID), "manifest.json"), manifest)
+}
+
+// LoadMediaManifest loads the media manifest of a game
+func (m *Manager) LoadMediaManifest(gameID string) (*MediaManifest, error) {
+	data, err := os.ReadFile(filepath.Join(m.MediaDir(gameID), "manifest.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	var manifest MediaManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, err
+	}
+
+	return &manifest, nil
+}
+
+// VerifyMedia checks every file in a game's media manifest against its recorded size
+// and checksum. The manifest may come from any backend; the files are always on disk.
+func (m *Manager) VerifyMedia(gameID string, manifest *MediaManifest) []MediaProblem {
+	var problems []MediaProblem
+	mediaDir := m.MediaDir(gameID)
+	for _, media := range manifest.Files {
+		size, sum, err := hashFile(filepath.Join(mediaDir, media.Name))
+		switch {
+		case err != nil:
+			problems = append(problems, MediaProblem{Name: media.Name, Problem: err.Error()})
+		case size != media.Size:
+			problems = append(problems, MediaProblem{Name: media.Name, Problem: fmt.Sprintf("size is %d bytes, expected %d", size, media.Size)})
+		case sum != media.SHA256:
+			problems = append(problems, MediaProblem{Name: media.Name, Problem: "checksum mismatch"})
+		}
+	}
+
+	return problems
+}
+
+// VerifyDownloads checks every downloaded file of a submission against its recorded
+// size and checksum
+func (m *Manager) VerifyDownloads(game *fetcher.GameSubmission) []MediaProblem {
+	var problems []MediaProblem
+	for _, download := range game.Downloads {
+		if download.Path == "" {
+			continue
+		}
+		size, sum, err := hashFile(m.AbsPath(download.Path))
+		switch {
+		case err != nil:
+			problems = append(problems, MediaProblem{Name: download.Path, Problem: err.Error()})
+		case download.Bytes > 0 && size != download.Bytes:
+			problems = append(problems, MediaProblem{Name: download.Path, Problem: fmt.Sprintf("size is %d bytes, expected %d", size, download.Bytes)})
+		case download.SHA256 != "" && sum != download.SHA256:
+			problems = append(problems, MediaProblem{Name: download.Path, Problem: "checksum mismatch"})
+		}
+	}
+
+	return problems
+}
+
+// hashFile returns the size and SHA-256 of a file
+func hashFile(path string) (int64, string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	size, err := io.Copy(hash, file)
+	if err != nil {
+		return 0, "", err
+	}
+
+	return size, hex.EncodeToString(hash.Sum(nil)), nil
+}
@@ -0,0 +1,89 @@
+package storage
+
+import (
+	"path/filepath"
+	"time"
+
+	"Itchalyser/config"
+)
+
+// Run, jam and game outcomes in a run report
+const (
+	OutcomeDone        = "done"        // Everything finished
+	OutcomePartial     = "partial"     // Finished, but some jams or games failed
+	OutcomeFailed      = "failed"      // Nothing usable was produced
+	OutcomeInterrupted = "interrupted" // Cancelled by a signal before finishing
+	OutcomeStopped     = "stopped"     // A run budget ran out before finishing
+	OutcomeSkipped     = "skipped"     // Finished by an earlier run and not processed again
+	OutcomeUnchanged   = "unchanged"   // Unchanged since the last scrape, only stats refreshed
+	OutcomeGone        = "gone"        // Removed from itch.io, kept with its entry data only
+	OutcomeNotStarted  = "not_started" // Never reached because the run ended early
+)
+
+// RunReport is the structured summary of a run, saved to the output directory so
+// scheduled runs can be checked afterwards
+type RunReport struct {
+	StartedAt       time.Time     `json:"started_at"`
+	FinishedAt      time.Time     `json:"finished_at"`
+	DurationSeconds float64       `json:"duration_seconds"`
+	Outcome         string        `json:"outcome"`
+	StopReason      string        `json:"stop_reason,omitempty"` // Budget or signal that ended the run early
+	ExitCode        int           `json:"exit_code"`
+	Requests        int64         `json:"requests"`
+	Retries         int64         `json:"retries"`
+	BytesDownloaded int64         `json:"bytes_downloaded"`
+	Config          config.Config `json:"config"` // Configuration of the run, without credentials
+	Jams            []*JamReport  `json:"jams"`
+}
+
+// JamReport summarises one jam of a run
+type JamReport struct {
+	Input           string        `json:"input"` // Jam as given on the command line
+	JamID           string        `json:"jam_id,omitempty"`
+	NumericID       string        `json:"numeric_id,omitempty"`
+	Title           string        `json:"title,omitempty"`
+	Outcome         string        `json:"outcome"`
+	Error           string        `json:"error,omitempty"`
+	Entries         int           `json:"entries"`
+	Selected        int           `json:"selected"`
+	Finished        int           `json:"finished"`
+	Failed          int           `json:"failed"`
+	Skipped         int           `json:"skipped"`
+	Unchanged       int           `json:"unchanged"`
+	Gone            int           `json:"gone"`         // Games removed from itch.io
+	ParseErrors     int           `json:"parse_errors"` // Responses that could not be parsed, a sign of a layout change
+	StartedAt       time.Time     `json:"started_at"`
+	DurationSeconds float64       `json:"duration_seconds"`
+	Games           []*GameReport `json:"games"`
+}
+
+// GameReport summarises one game of a jam in a run
+type GameReport struct {
+	GameID          string   `json:"game_id"`
+	Title           string   `json:"title"`
+	Outcome         string   `json:"outcome"`
+	Errors          []string `json:"errors,omitempty"`
+	Skipped         []string `json:"skipped,omitempty"` // Media and files left out on purpose
+	Requests        int64    `json:"requests"`
+	Retries         int64    `json:"retries"`
+	BytesDownloaded int64    `json:"bytes_downloaded"`
+	DurationSeconds float64  `json:"duration_seconds"`
+}
+
+// runReportLayout names run reports after their start time. The fixed-width fraction
+// keeps runs started in the same second apart and the names in chronological order.
+const runReportLayout = "20060102T150405.000000000Z"
+
+// RunReportPath returns where the report of a run started at the given time is saved
+func (m *Manager) RunReportPath(startedAt time.Time) string {
+	return filepath.Join(m.baseDir, "runs", startedAt.UTC().Format(runReportLayout)+".json")
+}
+
+// SaveRunReport saves a run report under runs/, named after the run's start time
+func (m *Manager) SaveRunReport(report *RunReport) error {
+	path := m.RunReportPath(report.StartedAt)
+	if err := m.CreateDirectory(filepath.Dir(path)); err != nil {
+		return err
+	}
+	return m.saveJSONToFile(path, report)
+}
@@ -0,0 +1,30 @@
+package storage
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRunReportPath(t *testing.T) {
+	m := NewManager("out")
+	started := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		startedAt time.Time
+		want      string
+	}{
+		{"whole second", started, "20250301T120000.000000000Z.json"},
+		{"same second", started.Add(1500 * time.Microsecond), "20250301T120000.001500000Z.json"},
+		{"local time", started.In(time.FixedZone("CET", 3600)), "20250301T120000.000000000Z.json"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, want := m.RunReportPath(tt.startedAt), filepath.Join("out", "runs", tt.want); got != want {
+				t.Errorf("RunReportPath = %s, want %s", got, want)
+			}
+		})
+	}
+}
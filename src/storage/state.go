@@ -0,0 +1,125 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// GameStatus is the crawl status of a single game within a jam
+type GameStatus string
+
+// Crawl statuses, in the order a game normally moves through them
+const (
+	StatusPending        GameStatus = "pending"         // Not processed yet
+	StatusDetailsFetched GameStatus = "details_fetched" // Rate page scraped and saved
+	StatusMediaDone      GameStatus = "media_done"      // All work for the game finished
+	StatusFailed         GameStatus = "failed"          // Some request failed after all retries
+	StatusGone           GameStatus = "gone"            // The entry was removed from itch.io
+)
+
+// GameState records how far a game got in the crawl
+type GameState struct {
+	Status      GameStatus `json:"status"`
+	LastFetched time.Time  `json:"last_fetched,omitempty"`
+	Attempts    int        `json:"attempts"`
+	Error       string     `json:"error,omitempty"`
+}
+
+// CrawlState is the persistent progress of crawling one jam
+type CrawlState struct {
+	JamID     string                `json:"jam_id"`
+	UpdatedAt time.Time             `json:"updated_at"`
+	Games     map[string]*GameState `json:"games"`
+
+	mu      sync.Mutex
+	unsaved int
+}
+
+// NewCrawlState creates an empty crawl state for a jam
+func NewCrawlState(jamID string) *CrawlState {
+	return &CrawlState{
+		JamID: jamID,
+		Games: make(map[string]*GameState),
+	}
+}
+
+// Status returns the recorded status of a game, or pending if it is unknown
+func (s *CrawlState) Status(gameID string) GameStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if game, ok := s.Games[gameID]; ok {
+		return game.Status
+	}
+	return StatusPending
+}
+
+// Update records a new status for a game and returns the number of changes not yet saved
+func (s *CrawlState) Update(gameID string, status GameStatus, err error) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	game, ok := s.Games[gameID]
+	if !ok {
+		game = &GameState{}
+		s.Games[gameID] = game
+	}
+
+	if status == StatusPending {
+		game.Attempts++
+	} else {
+		game.LastFetched = time.Now()
+	}
+	game.Status = status
+	game.Error = ""
+	if err != nil {
+		game.Error = err.Error()
+	}
+
+	s.unsaved++
+	return s.unsaved
+}
+
+// crawlStatePath returns the location of a jam's crawl state file
+func (m *Manager) crawlStatePath(jamID string) string {
+	return filepath.Join(m.baseDir, "jams", jamID, "state.json")
+}
+
+// LoadCrawlState loads the crawl state of a jam, returning an empty state if none was saved yet
+func (m *Manager) LoadCrawlState(jamID string) (*CrawlState, error) {
+	data, err := os.ReadFile(m.crawlStatePath(jamID))
+	if errors.Is(err, os.ErrNotExist) {
+		return NewCrawlState(jamID), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	state := NewCrawlState(jamID)
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, err
+	}
+	if state.Games == nil {
+		state.Games = make(map[string]*GameState)
+	}
+
+	return state, nil
+}
+
+// SaveCrawlState writes the crawl state of a jam to disk
+func (m *Manager) SaveCrawlState(state *CrawlState) error {
+	state.mu.Lock()
+	defer state.mu.Unlock()
+
+	state.UpdatedAt = time.Now()
+	if err := m.saveJSONToFile(m.crawlStatePath(state.JamID), state); err != nil {
+		return err
+	}
+
+	state.unsaved = 0
+	return nil
+}
@@ -1,18 +1,23 @@
 package storage
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
 
 	"Itchalyser/fetcher"
+	"Itchalyser/fsutil"
 )
 
 // Manager handles storage operations
 type Manager struct {
-	baseDir string
+	baseDir    string
+	jsonlMutex sync.Mutex
 }
 
 // NewManager creates a new storage manager
@@ -38,6 +43,12 @@ func (m *Manager) SaveJamMetadata(jamID string, metadata *fetcher.JamMetadata) e
 	return m.saveJSONToFile(metaPath, metadata)
 }
 
+// SaveJamResults saves the final rankings of a jam to a file
+func (m *Manager) SaveJamResults(jamID string, results *fetcher.JamResults) error {
+	resultsPath := filepath.Join(m.baseDir, "jams", jamID, "results.json")
+	return m.saveJSONToFile(resultsPath, results)
+}
+
 // SaveGameSubmission saves a game submission to a file
 func (m *Manager) SaveGameSubmission(jamID, gameID string, game *fetcher.GameSubmission) error {
 	gameDir := filepath.Join(m.baseDir, "jams", jamID, "submissions", gameID)
@@ -45,18 +56,47 @@ func (m *Manager) SaveGameSubmission(jamID, gameID string, game *fetcher.GameSub
 		return err
 	}
 	
-	// Create media directory
-	mediaDir := filepath.Join(gameDir, "media")
-	if err := m.CreateDirectory(mediaDir); err != nil {
-		return err
-	}
-	
 	gamePath := filepath.Join(gameDir, "game.json")
 	return m.saveJSONToFile(gamePath, game)
 }
 
+// SubmissionsJSONLPath returns the path of the JSON Lines file holding all submissions of a jam
+func (m *Manager) SubmissionsJSONLPath(jamID string) string {
+	return filepath.Join(m.baseDir, "jams", jamID, "submissions.jsonl")
+}
+
+// ResetJSONL truncates a JSON Lines file so a new run does not append to stale lines
+func (m *Manager) ResetJSONL(filePath string) error {
+	m.jsonlMutex.Lock()
+	defer m.jsonlMutex.Unlock()
+
+	if err := m.CreateDirectory(filepath.Dir(filePath)); err != nil {
+		return err
+	}
+
+	return fsutil.WriteFile(filePath, nil)
+}
+
+// LoadGameSubmission loads a previously saved game submission
+func (m *Manager) LoadGameSubmission(jamID, gameID string) (*fetcher.GameSubmission, error) {
+	data, err := os.ReadFile(m.submissionPath(jamID, gameID))
+	if err != nil {
+		return nil, err
+	}
+
+	var game fetcher.GameSubmission
+	if err := json.Unmarshal(data, &game); err != nil {
+		return nil, err
+	}
+
+	return &game, nil
+}
+
 // AppendToJSONL appends a JSON object to a JSON Lines file
 func (m *Manager) AppendToJSONL(filePath string, obj interface{}) error {
+	m.jsonlMutex.Lock()
+	defer m.jsonlMutex.Unlock()
+
 	// Create directory if it doesn't exist
 	dir := filepath.Dir(filePath)
 	if err := m.CreateDirectory(dir); err != nil {
@@ -69,7 +109,8 @@ func (m *Manager) AppendToJSONL(filePath string, obj interface{}) error {
 		return err
 	}
 	
-	// Open file in append mode
+	// Open file in append mode. Appends cannot go through a rename; each line is
+	// written with a single call so readers never see half a line from a healthy run.
 	file, err := os.OpenFile(filePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
 	if err != nil {
 		return err
@@ -90,94 +131,160 @@ func (m *Manager) GenerateMarkdownReport(jamID string, metadata *fetcher.JamMeta
 	
 	reportPath := filepath.Join(reportDir, fmt.Sprintf("%s-report.md", jamID))
 	
-	// Open file for writing
-	file, err := os.Create(reportPath)
-	if err != nil {
-		return err
-	}
-	defer file.Close()
+	// Build the report in memory and write it in one go
+	report := &bytes.Buffer{}
 	
 	// Write markdown header
-	file.WriteString(fmt.Sprintf("# %s\n\n", metadata.Title))
+	report.WriteString(fmt.Sprintf("# %s\n\n", metadata.Title))
 	
 	// Write jam metadata
-	file.WriteString("## Jam Details\n\n")
-	file.WriteString(fmt.Sprintf("- **ID**: %s\n", metadata.ID))
-	file.WriteString(fmt.Sprintf("- **Theme**: %s\n", metadata.Theme))
+	report.WriteString("## Jam Details\n\n")
+	report.WriteString(fmt.Sprintf("- **ID**: %s\n", metadata.ID))
+	report.WriteString(fmt.Sprintf("- **Theme**: %s\n", metadata.Theme))
 	
 	// Write host information
 	if len(metadata.Hosts) > 0 {
-		file.WriteString("- **Hosts**: ")
+		report.WriteString("- **Hosts**: ")
 		hosts := make([]string, 0, len(metadata.Hosts))
 		for _, host := range metadata.Hosts {
 			hosts = append(hosts, fmt.Sprintf("[%s](%s)", host.Name, host.URL))
 		}
-		file.WriteString(strings.Join(hosts, ", ") + "\n")
+		report.WriteString(strings.Join(hosts, ", ") + "\n")
 	}
 	
 	// Write dates
-	file.WriteString(fmt.Sprintf("- **Start Date**: %s\n", metadata.StartDate))
-	file.WriteString(fmt.Sprintf("- **End Date**: %s\n", metadata.EndDate))
-	file.WriteString(fmt.Sprintf("- **Submission Date**: %s\n", metadata.SubmissionDate))
+	report.WriteString(fmt.Sprintf("- **Start Date**: %s\n", metadata.StartDate))
+	report.WriteString(fmt.Sprintf("- **End Date**: %s\n", metadata.EndDate))
+	report.WriteString(fmt.Sprintf("- **Submission Date**: %s\n", metadata.SubmissionDate))
 	
 	// Write stats
-	file.WriteString(fmt.Sprintf("- **Submissions**: %s\n", metadata.SubmissionCount))
-	file.WriteString(fmt.Sprintf("- **Ratings**: %s\n", metadata.RatingCount))
-	file.WriteString(fmt.Sprintf("- **Comments**: %s\n", metadata.CommentsCount))
+	report.WriteString(fmt.Sprintf("- **Submissions**: %s\n", metadata.SubmissionCount))
+	report.WriteString(fmt.Sprintf("- **Ratings**: %s\n", metadata.RatingCount))
+	report.WriteString(fmt.Sprintf("- **Comments**: %s\n", metadata.CommentsCount))
 	
+	// Write leaderboard if the jam has results
+	writeLeaderboard(report, games)
+
 	// Write submissions
-	file.WriteString("\n## Game Submissions\n\n")
+	report.WriteString("\n## Game Submissions\n\n")
 	
 	for _, game := range games {
-		file.WriteString(fmt.Sprintf("### %s\n\n", game.Title))
+		report.WriteString(fmt.Sprintf("### %s\n\n", game.Title))
 		
-		file.WriteString(fmt.Sprintf("- **URL**: [Play on itch.io](%s)\n", game.URL))
+		report.WriteString(fmt.Sprintf("- **URL**: [Play on itch.io](%s)\n", game.URL))
 		
 		// Write authors
 		if len(game.Authors) > 0 {
-			file.WriteString("- **Authors**: ")
+			report.WriteString("- **Authors**: ")
 			authors := make([]string, 0, len(game.Authors))
 			for _, author := range game.Authors {
 				authors = append(authors, fmt.Sprintf("[%s](%s)", author.Name, author.URL))
 			}
-			file.WriteString(strings.Join(authors, ", ") + "\n")
+			report.WriteString(strings.Join(authors, ", ") + "\n")
 		}
 		
-		file.WriteString(fmt.Sprintf("- **Platforms**: %s\n", strings.Join(game.Platforms, ", ")))
-		file.WriteString(fmt.Sprintf("- **Created**: %s\n", game.CreatedAt))
-		file.WriteString(fmt.Sprintf("- **Ratings**: %d\n", game.RatingCount))
+		report.WriteString(fmt.Sprintf("- **Platforms**: %s\n", strings.Join(game.Platforms, ", ")))
+		report.WriteString(fmt.Sprintf("- **Created**: %s\n", game.CreatedAt))
+		if game.Genre != "" {
+			report.WriteString(fmt.Sprintf("- **Genre**: %s\n", game.Genre))
+		}
+		if len(game.Tags) > 0 {
+			report.WriteString(fmt.Sprintf("- **Tags**: %s\n", strings.Join(game.Tags, ", ")))
+		}
+		if len(game.MadeWith) > 0 {
+			report.WriteString(fmt.Sprintf("- **Made With**: %s\n", strings.Join(game.MadeWith, ", ")))
+		}
+		report.WriteString(fmt.Sprintf("- **Ratings**: %d\n", game.RatingCount))
+		if game.Results != nil && game.Results.Rank > 0 {
+			report.WriteString(fmt.Sprintf("- **Rank**: #%d (Score: %.3f)\n", game.Results.Rank, game.Results.Score))
+		}
 		
 		// Write description
 		if game.Description != "" {
-			file.WriteString("\n**Description**:\n\n")
-			file.WriteString(game.Description + "\n\n")
+			report.WriteString("\n**Description**:\n\n")
+			report.WriteString(game.Description + "\n\n")
 		}
 		
 		// Write criteria responses
 		if len(game.CriteriaResponses) > 0 {
-			file.WriteString("**Criteria Responses**:\n\n")
+			report.WriteString("**Criteria Responses**:\n\n")
 			for question, answer := range game.CriteriaResponses {
-				file.WriteString(fmt.Sprintf("- **%s**: %s\n", formatCriteriaKey(question), answer))
+				report.WriteString(fmt.Sprintf("- **%s**: %s\n", formatCriteriaKey(question), answer))
 			}
-			file.WriteString("\n")
+			report.WriteString("\n")
 		}
 		
 		// Write downloads
 		if len(game.Downloads) > 0 {
-			file.WriteString("**Downloads**:\n\n")
+			report.WriteString("**Downloads**:\n\n")
 			for _, download := range game.Downloads {
-				file.WriteString(fmt.Sprintf("- %s (%s) - For %s\n", 
+				report.WriteString(fmt.Sprintf("- %s (%s) - For %s\n", 
 					download.Filename, 
 					download.Size, 
 					strings.Join(download.Platforms, ", ")))
 			}
-			file.WriteString("\n")
+			report.WriteString("\n")
 		}
 		
-		file.WriteString("---\n\n")
+		report.WriteString("---\n\n")
 	}
 	
-	return nil
+	return fsutil.WriteFile(reportPath, report.Bytes())
+}
+
+// writeLeaderboard writes a table of ranked games ordered by overall rank, with one column per criterion
+func writeLeaderboard(report *bytes.Buffer, games []*fetcher.GameSubmission) {
+	var ranked []*fetcher.GameSubmission
+	var criteria []string
+	seen := make(map[string]bool)
+	for _, game := range games {
+		if game.Results == nil || game.Results.Rank == 0 {
+			continue
+		}
+		ranked = append(ranked, game)
+		for _, criterion := range game.Results.Criteria {
+			if !seen[criterion.Name] {
+				seen[criterion.Name] = true
+				criteria = append(criteria, criterion.Name)
+			}
+		}
+	}
+	if len(ranked) == 0 {
+		return
+	}
+
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return ranked[i].Results.Rank < ranked[j].Results.Rank
+	})
+
+	report.WriteString("\n## Leaderboard\n\n")
+	report.WriteString("| Rank | Game | Score | Raw Score | Ratings |")
+	for _, name := range criteria {
+		report.WriteString(fmt.Sprintf(" %s |", name))
+	}
+	report.WriteString("\n|---:|---|---:|---:|---:|" + strings.Repeat("---:|", len(criteria)) + "\n")
+
+	for _, game := range ranked {
+		results := game.Results
+		report.WriteString(fmt.Sprintf("| #%d | [%s](%s) | %.3f | %.3f | %d |",
+			results.Rank,
+			strings.ReplaceAll(game.Title, "|", "\\|"),
+			game.URL,
+			results.Score,
+			results.RawScore,
+			results.RatingCount))
+		for _, name := range criteria {
+			cell := " |"
+			for _, criterion := range results.Criteria {
+				if criterion.Name == name {
+					cell = fmt.Sprintf(" #%d (%.3f) |", criterion.Rank, criterion.Score)
+					break
+				}
+			}
+			report.WriteString(cell)
+		}
+		report.WriteString("\n")
+	}
 }
 
 // saveJSONToFile saves an object as JSON to a file
@@ -194,8 +301,8 @@ func (m *Manager) saveJSONToFile(path string, obj interface{}) error {
 		return err
 	}
 	
-	// Write to file
-	return os.WriteFile(path, jsonBytes, 0644)
+	// Write to a temporary file and rename it into place
+	return fsutil.WriteFile(path, jsonBytes)
 }
 
 // formatCriteriaKey formats a criteria key for better readability
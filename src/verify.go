@@ -0,0 +1,101 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"Itchalyser/storage"
+)
+
+// runVerify is the verify command. It checks that every stored jam can be read and that
+// media and game files match the sizes and checksums recorded when they were downloaded.
+func runVerify(args []string) int {
+	fs := flag.NewFlagSet("verify", flag.ExitOnError)
+	archive := addArchiveFlags(fs)
+	fs.Parse(args)
+
+	store, backend := openBackend(*archive.dir, *archive.backend)
+	defer backend.Close()
+
+	// Load jams one by one, so a broken jam is reported instead of stopping the check
+	jamIDs, err := backend.ListJams()
+	if err != nil {
+		log.Printf("Error listing jams: %v", err)
+		return exitFailed
+	}
+	if selected := archive.jamIDs(); selected != nil {
+		jamIDs = selected
+	}
+	if len(jamIDs) == 0 {
+		log.Printf("No scraped jams found in %s", *archive.dir)
+		return exitFailed
+	}
+
+	problems := 0
+	report := func(format string, args ...any) {
+		problems++
+		fmt.Printf("  "+format+"\n", args...)
+	}
+
+	// Media and files are shared between jams, so each game is checked once
+	checked := make(map[string]bool)
+	for _, jamID := range jamIDs {
+		fmt.Printf("Jam %s\n", jamID)
+
+		jam, err := storage.OpenJam(backend, jamID)
+		if err != nil {
+			report("cannot open jam: %v", err)
+			continue
+		}
+
+		// Submissions are streamed, so a broken one is reported and the rest still checked
+		submissions, files := 0, 0
+		for game, err := range jam.Submissions() {
+			if err != nil {
+				report("cannot read submission: %v", err)
+				continue
+			}
+			submissions++
+			if checked[game.ID] {
+				continue
+			}
+			checked[game.ID] = true
+
+			if game.MediaDir != "" {
+				manifest, err := backend.LoadMediaManifest(game.ID)
+				switch {
+				case errors.Is(err, os.ErrNotExist):
+					report("game %s: no media manifest", game.ID)
+				case err != nil:
+					report("game %s: cannot read media manifest: %v", game.ID, err)
+				default:
+					for _, problem := range store.VerifyMedia(game.ID, manifest) {
+						report("game %s: media %s: %s", game.ID, problem.Name, problem.Problem)
+					}
+					files += len(manifest.Files)
+				}
+			}
+
+			for _, problem := range store.VerifyDownloads(game) {
+				report("game %s: file %s: %s", game.ID, problem.Name, problem.Problem)
+			}
+			for _, download := range game.Downloads {
+				if download.Path != "" {
+					files++
+				}
+			}
+		}
+
+		fmt.Printf("  %d submissions, %d files checked\n", submissions, files)
+	}
+
+	if problems > 0 {
+		fmt.Printf("Found %d problems. Scrape the affected jams again to repair them.\n", problems)
+		return exitPartial
+	}
+	fmt.Println("No problems found.")
+	return exitOK
+}